@@ -3,6 +3,7 @@ package api
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
 	"reflect"
@@ -68,25 +69,35 @@ func (b *Binder) Bind(i interface{}, c echo.Context) error {
 }
 
 // ParsedData returns parsed map[string]interface from body.
-// Currently only parses JSON payload.
+// Parses JSON, MessagePack and CBOR payloads. Binary payloads are decoded directly, so numbers
+// can be of any integer or float type depending on their encoding.
 func ParsedData(c echo.Context) (map[string]interface{}, error) { // nolint: interfacer
 	data := c.Get(contextParsedDataKey)
 	if data != nil {
 		return data.(map[string]interface{}), nil
 	}
 
+	f := requestFormat(c)
+	if f == nil {
+		return nil, echo.ErrUnsupportedMediaType
+	}
+
 	req := c.Request()
-	ctype := req.Header.Get(echo.HeaderContentType)
 	dataMap := make(map[string]interface{})
 
-	switch {
-	case strings.HasPrefix(ctype, echo.MIMEApplicationJSON):
+	if f == jsonFormat {
 		if err := jsonConfig().NewDecoder(req.Body).Decode(&dataMap); err != nil {
 			return nil, err
 		}
+	} else {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
 
-	default:
-		return nil, echo.ErrUnsupportedMediaType
+		if err := f.unmarshal(b, &dataMap); err != nil {
+			return nil, err
+		}
 	}
 
 	c.Set(contextParsedDataKey, dataMap)
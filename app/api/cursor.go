@@ -0,0 +1,87 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/settings"
+)
+
+// CursorQuery is a query param holding signed pagination cursor.
+const CursorQuery = "cursor"
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// signCursor returns signature of cursor payload bound to path it was issued for. Path identifies route
+// along with its instance and class, so that cursor cannot be replayed against other list.
+func signCursor(path string, payload []byte) []byte {
+	h := hmac.New(sha256.New, []byte(settings.Common.SecretKey))
+	h.Write([]byte(path)) // nolint: errcheck
+	h.Write([]byte{0})    // nolint: errcheck
+	h.Write(payload)      // nolint: errcheck
+
+	return h.Sum(nil)
+}
+
+// EncodeCursor returns opaque token of pagination params for path signed with secret key.
+func EncodeCursor(path string, params url.Values) string {
+	payload := []byte(params.Encode())
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signCursor(path, payload))
+}
+
+// DecodeCursor verifies token signature for path and returns pagination params encoded in it.
+func DecodeCursor(path, token string) (url.Values, error) {
+	t := strings.SplitN(token, ".", 2)
+	if len(t) != 2 {
+		return nil, errInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(t[0])
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(t[1])
+	if err != nil || !hmac.Equal(sig, signCursor(path, payload)) {
+		return nil, errInvalidCursor
+	}
+
+	return url.ParseQuery(string(payload))
+}
+
+// CursorParams replaces signed pagination cursor with params encoded in it, so that handlers can process them
+// the same way as plain query params. Params of cursor take precedence. Needs to run before query params are accessed.
+func CursorParams(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		q := req.URL.Query()
+
+		token := q.Get(CursorQuery)
+		if token == "" {
+			return next(c)
+		}
+
+		params, err := DecodeCursor(req.URL.Path, token)
+		if err != nil {
+			return NewError(http.StatusBadRequest, map[string]interface{}{CursorQuery: []string{"Invalid cursor."}})
+		}
+
+		q.Del(CursorQuery)
+
+		for k, v := range params {
+			q[k] = v
+		}
+
+		req.URL.RawQuery = q.Encode()
+
+		return next(c)
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCursor(t *testing.T) {
+	Convey("Given cursor of pagination params issued for path", t, func() {
+		path := "/v3/instances/i/classes/cls/objects/"
+		params := url.Values{"last_pk": {"10"}, "order_by": {"-name"}}
+		token := EncodeCursor(path, params)
+
+		Convey("it decodes to the same params for the same path", func() {
+			decoded, err := DecodeCursor(path, token)
+			So(err, ShouldBeNil)
+			So(decoded, ShouldResemble, params)
+		})
+		Convey("it is rejected for other path", func() {
+			_, err := DecodeCursor("/v3/instances/i/classes/other/objects/", token)
+			So(err, ShouldEqual, errInvalidCursor)
+		})
+		Convey("tampered payload is rejected", func() {
+			parts := strings.SplitN(token, ".", 2)
+			forged := EncodeCursor(path, url.Values{"last_pk": {"1"}, "order_by": {"-name"}})
+
+			_, err := DecodeCursor(path, strings.SplitN(forged, ".", 2)[0]+"."+parts[1])
+			So(err, ShouldEqual, errInvalidCursor)
+		})
+		Convey("malformed token is rejected", func() {
+			for _, tok := range []string{"", "abc", "abc.!!", "!!." + strings.SplitN(token, ".", 2)[1]} {
+				_, err := DecodeCursor(path, tok)
+				So(err, ShouldEqual, errInvalidCursor)
+			}
+		})
+	})
+}
+
+func TestCursorParams(t *testing.T) {
+	Convey("Given cursor params middleware", t, func() {
+		path := "/v3/instances/i/classes/cls/objects/"
+		var query url.Values
+
+		handler := CursorParams(func(c echo.Context) error {
+			query = c.Request().URL.Query()
+			return nil
+		})
+		serve := func(target string) error {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			return handler(echo.New().NewContext(req, httptest.NewRecorder()))
+		}
+
+		Convey("params of cursor replace it and take precedence", func() {
+			token := EncodeCursor(path, url.Values{"last_pk": {"10"}})
+			So(serve(path+"?last_pk=1&page_size=5&cursor="+token), ShouldBeNil)
+			So(query, ShouldResemble, url.Values{"last_pk": {"10"}, "page_size": {"5"}})
+		})
+		Convey("cursor issued for other path results in bad request", func() {
+			token := EncodeCursor("/v3/instances/i/classes/other/objects/", url.Values{"last_pk": {"10"}})
+			err := serve(path + "?cursor=" + token)
+			So(err, ShouldNotBeNil)
+			So(err.(*Error).Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
@@ -75,7 +75,20 @@ func NewRevisionMismatchError(expected, current int) *Error {
 	}
 }
 
+// NewRevisionConflictError creates new revision mismatch error for updates of stale objects.
+func NewRevisionConflictError(expected, current int) *Error {
+	e := NewRevisionMismatchError(expected, current)
+	e.Code = http.StatusConflict
+
+	return e
+}
+
 // NewCountExceededError creates new count exceeded error.
 func NewCountExceededError(name string, limit int) *Error {
 	return NewGenericError(http.StatusBadRequest, fmt.Sprintf("%s count exceeded (%d).", name, limit))
 }
+
+// NewPreconditionFailedError creates new precondition failed error.
+func NewPreconditionFailedError() *Error {
+	return NewGenericError(http.StatusPreconditionFailed, "Resource has been modified since it was last retrieved.")
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRevisionErrors(t *testing.T) {
+	Convey("Given revision errors", t, func() {
+		Convey("mismatch error is a bad request", func() {
+			e := NewRevisionMismatchError(2, 3)
+			So(e.Code, ShouldEqual, http.StatusBadRequest)
+			So(e.Data, ShouldResemble, map[string]interface{}{"expected_revision": "Revision mismatch. Expected 2, got 3."})
+		})
+		Convey("conflict error is a conflict with the same details", func() {
+			e := NewRevisionConflictError(2, 3)
+			So(e.Code, ShouldEqual, http.StatusConflict)
+			So(e.Data, ShouldResemble, NewRevisionMismatchError(2, 3).Data)
+		})
+	})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Conditional request headers.
+const (
+	HeaderETag        = "ETag"
+	HeaderIfMatch     = "If-Match"
+	HeaderIfNoneMatch = "If-None-Match"
+)
+
+const (
+	etagAny        = "*"
+	etagWeakPrefix = "W/"
+)
+
+// ETag returns strong entity tag of object version identified by its revision and last update time.
+func ETag(revision int, updatedAt time.Time) string {
+	return fmt.Sprintf(`"%d-%x"`, revision, updatedAt.UnixNano())
+}
+
+// WeakETag returns weak entity tag of serialized response body.
+func WeakETag(b []byte) string {
+	return fmt.Sprintf(`%s"%x"`, etagWeakPrefix, bodyHash(b))
+}
+
+// representationETag returns strong entity tag of serialized representation of object version.
+// Body hash makes it vary with projection, expansion and format of response.
+func representationETag(etag string, b []byte) string {
+	return fmt.Sprintf(`%s-%x"`, strings.TrimSuffix(etag, `"`), bodyHash(b))
+}
+
+func bodyHash(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b) // nolint: errcheck
+
+	return h.Sum64()
+}
+
+// matchETag returns true if etag matches any of entity tags in header value.
+// Weak comparison ignores weak indicator, strong comparison never matches weak tags.
+func matchETag(header, etag string, weak bool) bool {
+	if weak {
+		etag = strings.TrimPrefix(etag, etagWeakPrefix)
+	} else if strings.HasPrefix(etag, etagWeakPrefix) {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == etagAny {
+			return true
+		}
+
+		if weak {
+			tag = strings.TrimPrefix(tag, etagWeakPrefix)
+		}
+
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchVersionETag returns true if header matches strong entity tag of object version
+// or of any of its representations.
+func matchVersionETag(header, etag string) bool {
+	prefix := strings.TrimSuffix(etag, `"`) + "-"
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == etagAny || tag == etag || (strings.HasPrefix(tag, prefix) && strings.HasSuffix(tag, `"`)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckIfMatch returns precondition failed error if request defines If-Match header that does not match
+// current entity tag of object. Tags of any representation of current object version match.
+// Should be called on locked object before it is modified.
+func CheckIfMatch(c echo.Context, etag string) error {
+	header := c.Request().Header.Get(HeaderIfMatch)
+	if header == "" || matchVersionETag(header, etag) {
+		return nil
+	}
+
+	return NewPreconditionFailedError()
+}
+
+// RenderWithETag outputs object along with strong entity tag of its serialized form derived from entity tag
+// of object version. Safe requests with If-None-Match header matching entity tag get empty 304 response instead.
+func RenderWithETag(c echo.Context, code int, obj interface{}, etag string) error {
+	f := responseFormat(c)
+
+	b, err := f.marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	return renderBlobWithETag(c, code, f, b, representationETag(etag, b))
+}
+
+// RenderWithWeakETag outputs object along with weak entity tag computed from its serialized form.
+// Useful for responses like list pages that have no single version.
+func RenderWithWeakETag(c echo.Context, code int, obj interface{}) error {
+	f := responseFormat(c)
+
+	b, err := f.marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	return renderBlobWithETag(c, code, f, b, WeakETag(b))
+}
+
+// renderBlobWithETag outputs serialized body along with entity tag or empty 304 response
+// if safe request defines If-None-Match header matching it.
+func renderBlobWithETag(c echo.Context, code int, f *format, b []byte, etag string) error {
+	c.Response().Header().Set(HeaderETag, etag)
+
+	if IsSafeMethod(c.Request().Method) && matchETag(c.Request().Header.Get(HeaderIfNoneMatch), etag, true) {
+		c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return renderBlob(c, code, f, b)
+}
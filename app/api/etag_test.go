@@ -0,0 +1,119 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestETag(t *testing.T) {
+	Convey("Given object version entity tag", t, func() {
+		etag := ETag(3, time.Unix(0, 255))
+		So(etag, ShouldEqual, `"3-ff"`)
+
+		Convey("representation tags differ with serialized form", func() {
+			tag := representationETag(etag, []byte(`{"a":1}`))
+			So(tag, ShouldStartWith, `"3-ff-`)
+			So(tag, ShouldNotEqual, representationETag(etag, []byte(`{"a":1,"b":2}`)))
+			So(tag, ShouldEqual, representationETag(etag, []byte(`{"a":1}`)))
+		})
+		Convey("weak tag is marked as weak", func() {
+			So(WeakETag([]byte("a")), ShouldStartWith, etagWeakPrefix)
+		})
+		Convey("strong comparison never matches weak tags", func() {
+			So(matchETag(`"a", "3-ff"`, etag, false), ShouldBeTrue)
+			So(matchETag(`W/"3-ff"`, etag, false), ShouldBeFalse)
+			So(matchETag(`"a"`, WeakETag([]byte("a")), false), ShouldBeFalse)
+		})
+		Convey("weak comparison ignores weak indicator", func() {
+			So(matchETag(`W/"3-ff"`, etag, true), ShouldBeTrue)
+			So(matchETag(`*`, etag, true), ShouldBeTrue)
+			So(matchETag(`"3-fe"`, etag, true), ShouldBeFalse)
+		})
+		Convey("version tag matches tags of its representations", func() {
+			So(matchVersionETag(representationETag(etag, []byte("a")), etag), ShouldBeTrue)
+			So(matchVersionETag(`"3-ff", "4-ff"`, etag), ShouldBeTrue)
+			So(matchVersionETag(representationETag(ETag(3, time.Unix(0, 4095)), []byte("a")), etag), ShouldBeFalse)
+			So(matchVersionETag(`W/"3-ff"`, etag), ShouldBeFalse)
+		})
+	})
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	Convey("Given If-Match request", t, func() {
+		etag := ETag(3, time.Unix(0, 255))
+		req := httptest.NewRequest(http.MethodPatch, "/", nil)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		Convey("missing header passes", func() {
+			So(CheckIfMatch(c, etag), ShouldBeNil)
+		})
+		Convey("representation tag of current version passes", func() {
+			req.Header.Set(HeaderIfMatch, representationETag(etag, []byte("a")))
+			So(CheckIfMatch(c, etag), ShouldBeNil)
+		})
+		Convey("tag of other version fails with precondition failed", func() {
+			req.Header.Set(HeaderIfMatch, ETag(2, time.Unix(0, 255)))
+			So(CheckIfMatch(c, etag), ShouldResemble, NewPreconditionFailedError())
+		})
+	})
+}
+
+func TestRenderWithETag(t *testing.T) {
+	Convey("Given object rendered with entity tag", t, func() {
+		etag := ETag(3, time.Unix(0, 255))
+		render := func(accept, ifNoneMatch string, obj interface{}) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAccept, accept)
+			req.Header.Set(HeaderIfNoneMatch, ifNoneMatch)
+
+			rec := httptest.NewRecorder()
+			So(RenderWithETag(echo.New().NewContext(req, rec), http.StatusOK, obj, etag), ShouldBeNil)
+
+			return rec
+		}
+		obj := map[string]interface{}{"a": 1}
+		rec := render("", "", obj)
+
+		Convey("response has representation tag", func() {
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Header().Get(HeaderETag), ShouldStartWith, `"3-ff-`)
+			So(rec.Header().Get(echo.HeaderVary), ShouldEqual, echo.HeaderAccept)
+		})
+		Convey("matching If-None-Match gets 304", func() {
+			rec = render("", rec.Header().Get(HeaderETag), obj)
+			So(rec.Code, ShouldEqual, http.StatusNotModified)
+			So(rec.Body.Len(), ShouldEqual, 0)
+		})
+		Convey("tag varies with response format", func() {
+			other := render(MIMEApplicationMsgpack, rec.Header().Get(HeaderETag), obj)
+			So(other.Code, ShouldEqual, http.StatusOK)
+			So(other.Header().Get(HeaderETag), ShouldNotEqual, rec.Header().Get(HeaderETag))
+		})
+		Convey("tag varies with serialized object", func() {
+			other := render("", rec.Header().Get(HeaderETag), map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 2}})
+			So(other.Code, ShouldEqual, http.StatusOK)
+			So(other.Header().Get(HeaderETag), ShouldNotEqual, rec.Header().Get(HeaderETag))
+		})
+	})
+}
+
+func TestRenderWithWeakETag(t *testing.T) {
+	Convey("Given object rendered with weak entity tag", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+		So(RenderWithWeakETag(echo.New().NewContext(req, rec), http.StatusOK, []int{1}), ShouldBeNil)
+
+		Convey("matching If-None-Match gets 304", func() {
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(HeaderIfNoneMatch, rec.Header().Get(HeaderETag))
+			rec = httptest.NewRecorder()
+			So(RenderWithWeakETag(echo.New().NewContext(req, rec), http.StatusOK, []int{1}), ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusNotModified)
+		})
+	})
+}
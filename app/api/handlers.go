@@ -202,6 +202,25 @@ func RateLimit(limiter *redis_rate.Limiter, instanceRateLimit, adminRateLimit, a
 	}
 }
 
+// ScopedRateLimit handles additional rate limit of expensive endpoints. It is counted separately for scope
+// per instance or per IP outside of instance scope.
+func ScopedRateLimit(limiter *redis_rate.Limiter, scope string, rate *settings.RateData) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			name := scope + ":" + c.RealIP()
+			if instance := c.Get(settings.ContextInstanceKey); instance != nil {
+				name = scope + ":i=" + strconv.Itoa(instance.(*models.Instance).ID)
+			}
+
+			if delay, allowed := checkLimit(limiter, name, rate); !allowed {
+				return rateLimitError(c, delay)
+			}
+
+			return next(c)
+		}
+	}
+}
+
 var validMethods = map[string]empty{
 	http.MethodGet:    {},
 	http.MethodPost:   {},
@@ -1,22 +1,40 @@
 package api
 
 import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"unsafe"
 
+	"github.com/fxamacker/cbor/v2"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v4"
 
 	"github.com/Syncano/orion/app/settings"
 )
 
+// Media types of supported binary formats.
+const (
+	MIMEApplicationMsgpack  = "application/msgpack"
+	MIMEApplicationXMsgpack = "application/x-msgpack"
+	MIMEApplicationCBOR     = "application/cbor"
+)
+
 var (
 	jsonConfigAPI  jsoniter.API
 	jsonConfigOnce sync.Once
+	cborEncMode    cbor.EncMode
+	cborDecMode    cbor.DecMode
+	cborModeOnce   sync.Once
 )
 
 // RawMessage is universal []byte type that is registered for all available output encoders.
-// Currently supports only JSON but it's easy to extend.
+// It always holds JSON, binary encoders convert it along with the rest of object.
 type RawMessage []byte
 
 func init() {
@@ -27,15 +45,99 @@ func init() {
 	})
 }
 
-// Render serializes and outputs object to http writer depending on content negotiation..
-// Currently supports only JSON but it's easy to extend.
+// format is a serialization format of request and response bodies.
+type format struct {
+	contentType string
+	marshal     func(v interface{}) ([]byte, error)
+	unmarshal   func(b []byte, v interface{}) error
+}
+
+var (
+	jsonFormat = &format{
+		contentType: echo.MIMEApplicationJSONCharsetUTF8,
+		marshal:     func(v interface{}) ([]byte, error) { return jsonConfig().Marshal(v) },
+		unmarshal:   func(b []byte, v interface{}) error { return jsonConfig().Unmarshal(b, v) },
+	}
+	msgpackFormat = &format{
+		contentType: MIMEApplicationMsgpack,
+		marshal:     marshalMsgpack,
+		unmarshal:   msgpack.Unmarshal,
+	}
+	cborFormat = &format{
+		contentType: MIMEApplicationCBOR,
+		marshal:     marshalCBOR,
+		unmarshal:   unmarshalCBOR,
+	}
+
+	// formats maps media types to formats. JSON is used when none of them is acceptable.
+	formats = map[string]*format{
+		echo.MIMEApplicationJSON: jsonFormat,
+		MIMEApplicationMsgpack:   msgpackFormat,
+		MIMEApplicationXMsgpack:  msgpackFormat,
+		MIMEApplicationCBOR:      cborFormat,
+	}
+)
+
+// responseFormat returns format negotiated with Accept header. Supported media type with highest quality
+// value wins, first one of them if there are more.
+func responseFormat(c echo.Context) *format {
+	var (
+		best     *format
+		bestQual float64
+	)
+
+	for _, accept := range strings.Split(c.Request().Header.Get(echo.HeaderAccept), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		f, ok := formats[mediaType]
+		if !ok {
+			continue
+		}
+
+		qual := 1.0
+
+		if q, ok := params["q"]; ok {
+			if qual, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		if qual > bestQual {
+			best, bestQual = f, qual
+		}
+	}
+
+	if best == nil {
+		return jsonFormat
+	}
+
+	return best
+}
+
+// requestFormat returns format of request body or nil if it is not supported.
+func requestFormat(c echo.Context) *format {
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	return formats[mediaType]
+}
+
+// Render serializes and outputs object to http writer depending on content negotiation.
 func Render(e echo.Context, code int, obj interface{}) error {
-	bytes, err := Marshal(e, obj)
+	f := responseFormat(e)
+
+	b, err := f.marshal(obj)
 	if err != nil {
 		return err
 	}
 
-	return e.JSONBlob(code, bytes)
+	return renderBlob(e, code, f, b)
+}
+
+func renderBlob(c echo.Context, code int, f *format, b []byte) error {
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	return c.Blob(code, f.contentType, b)
 }
 
 func jsonConfig() jsoniter.API {
@@ -50,7 +152,104 @@ func jsonConfig() jsoniter.API {
 }
 
 // Marshal serializes object depending on content negotiation.
-// Currently supports only JSON but it's easy to extend.
 func Marshal(c echo.Context, obj interface{}) ([]byte, error) {
+	return responseFormat(c).marshal(obj)
+}
+
+// MarshalRaw serializes object so that it can be embedded as RawMessage in output of any format.
+func MarshalRaw(obj interface{}) (RawMessage, error) {
 	return jsonConfig().Marshal(obj)
 }
+
+// jsonValue returns object as generic value of its JSON representation. This way binary formats respect
+// JSON tags and marshalers of serialized objects as well as RawMessages embedded in them.
+// Integral numbers are kept as integers.
+func jsonValue(obj interface{}) (interface{}, error) {
+	b, err := jsonConfig().Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var v interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return jsonNumbers(v), nil
+}
+
+// jsonNumbers replaces json numbers in value with int64 or float64.
+func jsonNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := val.Float64()
+
+		return f
+
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = jsonNumbers(item)
+		}
+
+	case []interface{}:
+		for i, item := range val {
+			val[i] = jsonNumbers(item)
+		}
+	}
+
+	return v
+}
+
+func marshalMsgpack(obj interface{}) ([]byte, error) {
+	v, err := jsonValue(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	enc := msgpack.NewEncoder(&buf).SortMapKeys(settings.Common.Debug)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func cborModes() (cbor.EncMode, cbor.DecMode) {
+	cborModeOnce.Do(func() {
+		sortMode := cbor.SortNone
+		if settings.Common.Debug {
+			sortMode = cbor.SortCanonical
+		}
+
+		cborEncMode, _ = cbor.EncOptions{Sort: sortMode}.EncMode()
+		cborDecMode, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+	})
+
+	return cborEncMode, cborDecMode
+}
+
+func marshalCBOR(obj interface{}) ([]byte, error) {
+	v, err := jsonValue(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	em, _ := cborModes()
+
+	return em.Marshal(v)
+}
+
+func unmarshalCBOR(b []byte, v interface{}) error {
+	_, dm := cborModes()
+	return dm.Unmarshal(b, v)
+}
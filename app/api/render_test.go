@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/vmihailenco/msgpack/v4"
+)
+
+func TestResponseFormat(t *testing.T) {
+	Convey("Given Accept header", t, func() {
+		format := func(accept string) *format {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAccept, accept)
+
+			return responseFormat(echo.New().NewContext(req, httptest.NewRecorder()))
+		}
+
+		Convey("JSON is used by default", func() {
+			So(format(""), ShouldEqual, jsonFormat)
+			So(format("text/html, */*"), ShouldEqual, jsonFormat)
+		})
+		Convey("supported media type is used", func() {
+			So(format("application/msgpack"), ShouldEqual, msgpackFormat)
+			So(format("application/x-msgpack"), ShouldEqual, msgpackFormat)
+			So(format("text/html, application/cbor"), ShouldEqual, cborFormat)
+		})
+		Convey("media type with highest quality wins", func() {
+			So(format("application/json;q=0.5, application/msgpack;q=0.9"), ShouldEqual, msgpackFormat)
+			So(format("application/cbor;q=0.2, application/json"), ShouldEqual, jsonFormat)
+		})
+		Convey("first of equally preferred media types wins", func() {
+			So(format("application/cbor, application/msgpack"), ShouldEqual, cborFormat)
+			So(format("application/msgpack;q=0.5, application/cbor;q=0.5"), ShouldEqual, msgpackFormat)
+		})
+		Convey("unacceptable and invalid entries are skipped", func() {
+			So(format("application/msgpack;q=0, application/cbor;q=0.1"), ShouldEqual, cborFormat)
+			So(format("application/msgpack;q=abc"), ShouldEqual, jsonFormat)
+		})
+	})
+}
+
+func TestRender(t *testing.T) {
+	Convey("Given object rendered with negotiated format", t, func() {
+		render := func(accept string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAccept, accept)
+
+			rec := httptest.NewRecorder()
+			So(Render(echo.New().NewContext(req, rec), http.StatusOK, map[string]interface{}{"a": RawMessage(`{"b":1}`)}), ShouldBeNil)
+
+			return rec
+		}
+
+		Convey("JSON embeds raw messages as is", func() {
+			rec := render("")
+			So(rec.Header().Get(echo.HeaderContentType), ShouldEqual, echo.MIMEApplicationJSONCharsetUTF8)
+			So(rec.Body.String(), ShouldEqual, `{"a":{"b":1}}`)
+		})
+		Convey("MessagePack converts raw messages along with the rest of object", func() {
+			rec := render(MIMEApplicationMsgpack)
+			So(rec.Header().Get(echo.HeaderContentType), ShouldEqual, MIMEApplicationMsgpack)
+			So(rec.Header().Get(echo.HeaderVary), ShouldEqual, echo.HeaderAccept)
+
+			var v map[string]interface{}
+			So(msgpack.Unmarshal(rec.Body.Bytes(), &v), ShouldBeNil)
+			So(v["a"], ShouldContainKey, "b")
+		})
+		Convey("CBOR is rendered with its content type", func() {
+			rec := render(MIMEApplicationCBOR)
+			So(rec.Header().Get(echo.HeaderContentType), ShouldEqual, MIMEApplicationCBOR)
+
+			var v map[string]interface{}
+			So(unmarshalCBOR(rec.Body.Bytes(), &v), ShouldBeNil)
+			So(v["a"], ShouldContainKey, "b")
+		})
+	})
+}
+
+func TestParsedData(t *testing.T) {
+	Convey("Given request body", t, func() {
+		parse := func(ctype string, body []byte) (map[string]interface{}, error) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, ctype)
+
+			return ParsedData(echo.New().NewContext(req, httptest.NewRecorder()))
+		}
+		obj := map[string]interface{}{"i": 5, "s": "x", "m": map[string]interface{}{"f": 1.5}}
+
+		Convey("JSON numbers are decoded as float64", func() {
+			data, err := parse(echo.MIMEApplicationJSON, []byte(`{"i": 5, "s": "x"}`))
+			So(err, ShouldBeNil)
+			So(data, ShouldResemble, map[string]interface{}{"i": 5.0, "s": "x"})
+		})
+		Convey("MessagePack is decoded directly", func() {
+			b, err := msgpack.Marshal(obj)
+			So(err, ShouldBeNil)
+
+			data, err := parse(MIMEApplicationMsgpack, b)
+			So(err, ShouldBeNil)
+			So(data["i"], ShouldNotHaveSameTypeAs, 5.0)
+			So(data["i"], ShouldEqual, 5)
+			So(data["s"], ShouldEqual, "x")
+			So(data["m"], ShouldResemble, map[string]interface{}{"f": 1.5})
+		})
+		Convey("CBOR is decoded directly", func() {
+			em, _ := cborModes()
+			b, err := em.Marshal(obj)
+			So(err, ShouldBeNil)
+
+			data, err := parse(MIMEApplicationCBOR, b)
+			So(err, ShouldBeNil)
+			So(data["i"], ShouldEqual, uint64(5))
+			So(data["s"], ShouldEqual, "x")
+			So(data["m"], ShouldResemble, map[string]interface{}{"f": 1.5})
+		})
+		Convey("unsupported media type results in error", func() {
+			_, err := parse(echo.MIMETextPlain, []byte("a"))
+			So(err, ShouldEqual, echo.ErrUnsupportedMediaType)
+		})
+	})
+}
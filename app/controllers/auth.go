@@ -22,8 +22,10 @@ import (
 var keyRegex = regexp.MustCompile(`^[a-f0-9]{40}$`)
 
 const (
-	apiKeyQuery  = "api_key"
-	apiKeyHeader = "X-API-Key"
+	apiKeyQuery   = "api_key"
+	apiKeyHeader  = "X-API-Key"
+	userKeyQuery  = "user_key"
+	userKeyHeader = "X-User-Key"
 )
 
 // Auth handles authenticates admin/api key.
@@ -90,7 +92,7 @@ func (ctr *Controller) AuthUser(next echo.HandlerFunc) echo.HandlerFunc {
 		if c.Get(settings.ContextInstanceKey) != nil {
 			form := &validators.UserKeyForm{}
 			if api.BindAndValidate(c, form) != nil {
-				form.UserKey = util.NonEmptyString(c.QueryParam("user_key"), c.Request().Header.Get("X-User-Key"))
+				form.UserKey = util.NonEmptyString(c.QueryParam(userKeyQuery), c.Request().Header.Get(userKeyHeader))
 			}
 
 			o := &models.User{Key: form.UserKey}
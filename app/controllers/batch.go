@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	json "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/query"
+	"github.com/Syncano/orion/app/settings"
+	"github.com/Syncano/orion/app/validators"
+)
+
+const (
+	batchPath      = "batch/"
+	batchSavepoint = "batch_request"
+)
+
+var errBatchRollback = errors.New("batch rolled back")
+
+type batchHooksContextKey struct{}
+
+// batchHooks are commit hooks added by batch request running in its own savepoint.
+type batchHooks []func() error
+
+// batchResult is a response of single request of batch.
+type batchResult struct {
+	Status int         `json:"status"`
+	Body   interface{} `json:"body"`
+}
+
+func newBatchError(detail string) *api.Error {
+	return api.NewError(http.StatusBadRequest, map[string]interface{}{"requests": detail})
+}
+
+// InstanceBatch dispatches requests against instance routes within single transaction.
+// With all_or_nothing, first failed request rolls back whole batch and stops processing. Otherwise every request
+// runs in its own savepoint so that failed ones do not affect the rest.
+func (ctr *Controller) InstanceBatch(c echo.Context) error {
+	v := &validators.BatchForm{}
+	if err := api.BindAndValidate(c, v); err != nil {
+		return err
+	}
+
+	if len(v.Requests) > settings.API.BatchMax {
+		return newBatchError(fmt.Sprintf("Too many requests defined (exceeds %d).", settings.API.BatchMax))
+	}
+
+	targets := make([]string, len(v.Requests))
+
+	for i, r := range v.Requests {
+		target, ok := batchTarget(c.Request().URL.Path, r.Path)
+		if !ok {
+			return newBatchError(fmt.Sprintf(`Invalid path specified: "%s".`, r.Path))
+		}
+
+		targets[i] = target
+	}
+
+	results := make([]*batchResult, 0, len(v.Requests))
+
+	err := ctr.q.NewDataObjectManager(c).RunInTransaction(func(tx *pg.Tx) error {
+		for i, r := range v.Requests {
+			res, err := ctr.batchExec(c, tx, targets[i], r, !v.AllOrNothing)
+			if err != nil {
+				return err
+			}
+
+			results = append(results, res)
+
+			if v.AllOrNothing && res.Status >= http.StatusBadRequest {
+				return errBatchRollback
+			}
+		}
+
+		return nil
+	})
+
+	switch {
+	case err == errBatchRollback:
+		return api.Render(c, http.StatusBadRequest, results)
+	case err != nil:
+		return err
+	}
+
+	return api.Render(c, http.StatusOK, results)
+}
+
+// batchTarget returns URI of batch request resolved against instance, e.g. "/classes/" of batch "/v3/instances/<name>/batch/"
+// is dispatched as "/v3/instances/<name>/classes/". Paths outside of instance or resolving to batch itself are invalid.
+func batchTarget(batchURLPath, p string) (string, bool) {
+	prefix := strings.TrimSuffix(batchURLPath, batchPath)
+
+	u, err := url.Parse(p)
+	if err != nil || u.Scheme != "" || u.Host != "" || strings.Contains(u.Path, "..") ||
+		path.Join(prefix, u.Path) == path.Clean(batchURLPath) {
+		return "", false
+	}
+
+	return prefix + strings.TrimPrefix(u.RequestURI(), "/"), true
+}
+
+// batchExec dispatches single request through router with auth of parent request and shared transaction.
+func (ctr *Controller) batchExec(c echo.Context, tx *pg.Tx, target string, r *validators.BatchRequestForm, savepoint bool) (*batchResult, error) {
+	var body io.Reader
+
+	if r.Body != nil {
+		b, err := json.Marshal(r.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		body = bytes.NewReader(b)
+	}
+
+	parent := c.Request()
+	ctx := query.WithTx(parent.Context(), tx)
+	hooks := &batchHooks{}
+
+	if savepoint {
+		ctx = context.WithValue(ctx, batchHooksContextKey{}, hooks)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Host = parent.Host
+	req.RemoteAddr = parent.RemoteAddr
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	for _, h := range []string{echo.HeaderXForwardedFor, echo.HeaderXRealIP} {
+		if val := parent.Header.Get(h); val != "" {
+			req.Header.Set(h, val)
+		}
+	}
+
+	setBatchAuth(c, req)
+
+	if savepoint {
+		if _, err := tx.Exec("SAVEPOINT " + batchSavepoint); err != nil {
+			return nil, err
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	c.Echo().ServeHTTP(rec, req)
+
+	if savepoint {
+		if err := endBatchSavepoint(tx, *hooks, rec.Code >= http.StatusBadRequest, func(f func() error) {
+			ctr.db.AddDBCommitHook(tx, f)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &batchResult{Status: rec.Code}
+
+	if b := rec.Body.Bytes(); len(b) > 0 {
+		if strings.HasPrefix(rec.Header().Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+			res.Body = api.RawMessage(b)
+		} else {
+			res.Body = string(b)
+		}
+	}
+
+	return res, nil
+}
+
+// endBatchSavepoint releases savepoint of batch request and passes its commit hooks to addHook so that they run
+// once batch is committed. Failed request is rolled back to savepoint and its hooks are dropped instead,
+// e.g. file replaced by it is not deleted from storage.
+func endBatchSavepoint(db orm.DB, hooks batchHooks, failed bool, addHook func(f func() error)) error {
+	if failed {
+		_, err := db.Exec("ROLLBACK TO SAVEPOINT " + batchSavepoint)
+		return err
+	}
+
+	if _, err := db.Exec("RELEASE SAVEPOINT " + batchSavepoint); err != nil {
+		return err
+	}
+
+	for _, f := range hooks {
+		addHook(f)
+	}
+
+	return nil
+}
+
+// setBatchAuth passes keys that authenticated parent request so that batch requests share its auth context.
+func setBatchAuth(c echo.Context, req *http.Request) {
+	if o, ok := c.Get(settings.ContextAdminKey).(*models.Admin); ok {
+		req.Header.Set(apiKeyHeader, o.Key)
+	} else if o, ok := c.Get(settings.ContextAPIKeyKey).(*models.APIKey); ok {
+		req.Header.Set(apiKeyHeader, o.Key)
+	}
+
+	if o, ok := c.Get(settings.ContextUserKey).(*models.User); ok {
+		req.Header.Set(userKeyHeader, o.Key)
+	}
+}
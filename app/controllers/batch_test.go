@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+)
+
+func TestBatchTarget(t *testing.T) {
+	Convey("Given batch request path", t, func() {
+		batch := "/v3/instances/test/batch/"
+
+		Convey("paths are resolved against instance", func() {
+			for p, expected := range map[string]string{
+				"/classes/":               "/v3/instances/test/classes/",
+				"classes/cls/objects/":    "/v3/instances/test/classes/cls/objects/",
+				"/classes/?page_size=10":  "/v3/instances/test/classes/?page_size=10",
+				"/classes/batch/objects/": "/v3/instances/test/classes/batch/objects/",
+			} {
+				target, ok := batchTarget(batch, p)
+				So(ok, ShouldBeTrue)
+				So(target, ShouldEqual, expected)
+			}
+		})
+		Convey("paths outside of instance are rejected", func() {
+			for _, p := range []string{"http://example.com/", "//example.com/", "/../other/", "classes/../../"} {
+				_, ok := batchTarget(batch, p)
+				So(ok, ShouldBeFalse)
+			}
+		})
+		Convey("paths resolving to batch itself are rejected", func() {
+			for _, p := range []string{"/batch/", "batch/", "batch", "./batch/", "/./batch", "/batch/?a=1", "/b%61tch/"} {
+				_, ok := batchTarget(batch, p)
+				So(ok, ShouldBeFalse)
+			}
+		})
+	})
+}
+
+func TestSetBatchAuth(t *testing.T) {
+	Convey("Given authenticated parent request", t, func() {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		Convey("API and user keys are passed to batch request", func() {
+			c.Set(settings.ContextAPIKeyKey, &models.APIKey{Key: "api"})
+			c.Set(settings.ContextUserKey, &models.User{Key: "user"})
+			setBatchAuth(c, req)
+
+			So(req.Header.Get(apiKeyHeader), ShouldEqual, "api")
+			So(req.Header.Get(userKeyHeader), ShouldEqual, "user")
+		})
+		Convey("admin key takes precedence", func() {
+			c.Set(settings.ContextAdminKey, &models.Admin{Key: "admin"})
+			c.Set(settings.ContextAPIKeyKey, &models.APIKey{Key: "api"})
+			setBatchAuth(c, req)
+
+			So(req.Header.Get(apiKeyHeader), ShouldEqual, "admin")
+			So(req.Header.Get(userKeyHeader), ShouldBeEmpty)
+		})
+	})
+}
+
+// testExecDB records executed statements.
+type testExecDB struct {
+	orm.DB
+	stmts []string
+}
+
+func (db *testExecDB) Exec(q interface{}, params ...interface{}) (orm.Result, error) {
+	db.stmts = append(db.stmts, q.(string))
+	return nil, nil
+}
+
+func TestBatchHooks(t *testing.T) {
+	Convey("Given batch request running in savepoint", t, func() {
+		ctr := &Controller{}
+		hooks := &batchHooks{}
+		ctx := context.WithValue(context.Background(), batchHooksContextKey{}, hooks)
+		db := &testExecDB{}
+
+		var called []string
+
+		ctr.addDBCommitHook(ctx, db, func() error {
+			called = append(called, "a")
+			return nil
+		})
+		ctr.addDBCommitHook(ctx, db, func() error {
+			called = append(called, "b")
+			return nil
+		})
+
+		var added batchHooks
+
+		addHook := func(f func() error) {
+			added = append(added, f)
+		}
+
+		Convey("commit hooks are collected by request", func() {
+			So(*hooks, ShouldHaveLength, 2)
+			So(called, ShouldBeEmpty)
+		})
+		Convey("hooks of successful request are added to batch transaction in order", func() {
+			So(endBatchSavepoint(db, *hooks, false, addHook), ShouldBeNil)
+			So(db.stmts, ShouldResemble, []string{"RELEASE SAVEPOINT " + batchSavepoint})
+			So(added, ShouldHaveLength, 2)
+
+			for _, f := range added {
+				So(f(), ShouldBeNil)
+			}
+
+			So(called, ShouldResemble, []string{"a", "b"})
+		})
+		Convey("hooks of request rolled back to savepoint are dropped", func() {
+			So(endBatchSavepoint(db, *hooks, true, addHook), ShouldBeNil)
+			So(db.stmts, ShouldResemble, []string{"ROLLBACK TO SAVEPOINT " + batchSavepoint})
+			So(added, ShouldBeEmpty)
+			So(called, ShouldBeEmpty)
+		})
+	})
+}
@@ -120,7 +120,7 @@ func (ctr *Controller) changeSubscribe(c echo.Context, room *string) error {
 
 	o := <-ch
 	if o != nil {
-		return c.JSONBlob(http.StatusOK, o)
+		return api.Render(c, http.StatusOK, api.RawMessage(o))
 	}
 
 	return c.NoContent(http.StatusNoContent)
@@ -147,7 +147,7 @@ func (ctr *Controller) changeSubscribeStream(c echo.Context, room *string, limit
 
 		var b []byte
 		for _, obj := range o {
-			b, err = api.Marshal(c, serializers.ChangeSerializer{}.Response(obj))
+			b, err = api.MarshalRaw(serializers.ChangeSerializer{}.Response(obj))
 			outCh <- b
 
 			lastID = obj.ID
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/go-pg/pg/v9"
@@ -8,7 +9,10 @@ import (
 
 	"github.com/Syncano/orion/app/api"
 	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/query"
 	"github.com/Syncano/orion/app/serializers"
+	"github.com/Syncano/orion/app/settings"
+	"github.com/Syncano/orion/app/validators"
 	"github.com/Syncano/pkg-go/v2/database/manager"
 )
 
@@ -25,17 +29,141 @@ func (ctr *Controller) ClassContext(next echo.HandlerFunc) echo.HandlerFunc {
 			return err
 		}
 
+		if err := checkClassPermission(c, ctr.q, o, requiredClassPermission(c)); err != nil {
+			return err
+		}
+
 		c.Set(contextClassKey, o)
 
 		return next(c)
 	}
 }
 
-func (ctr *Controller) ClassCreate(c echo.Context) error {
-	// TODO: #13 Class create
+// requiredClassPermission returns class permission needed by data object route of request.
+func requiredClassPermission(c echo.Context) int {
+	switch {
+	case c.Request().Method == http.MethodPost:
+		return models.ClassPermissionCreate
+	case c.Param("object_id") != "":
+		return models.ClassPermissionRead
+	default:
+		return models.ClassPermissionList
+	}
+}
+
+// classPermissionsApply returns true if class permissions apply to request. They apply to requests authenticated
+// with user key and to API keys that opt into them. Admins and API keys that ignore ACL are not subject to them.
+func classPermissionsApply(c echo.Context) bool {
+	if c.Get(settings.ContextAdminKey) != nil {
+		return false
+	}
+
+	key, _ := c.Get(settings.ContextAPIKeyKey).(*models.APIKey)
+	if key != nil && key.IgnoreACL() {
+		return false
+	}
+
+	return c.Get(settings.ContextUserKey) != nil || (key != nil && key.CheckClassPermissions())
+}
+
+// checkClassPermission checks that request has required permission to class.
+// Users that are members of class group get greater of group and other permissions.
+func checkClassPermission(c echo.Context, qf *query.Factory, class *models.Class, required int) error {
+	if !classPermissionsApply(c) || class.OtherPermissions >= required {
+		return nil
+	}
+
+	user, ok := c.Get(settings.ContextUserKey).(*models.User)
+	if ok && class.GroupID != 0 && class.GroupPermissions >= required {
+		exists, err := qf.NewUserMembershipManager(c).
+			ForUserAndGroupQ(&models.UserMembership{UserID: user.ID, GroupID: class.GroupID}).Exists()
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return nil
+		}
+	}
+
 	return api.NewPermissionDeniedError()
 }
 
+// ClassCreate creates class with validated schema. Indexes of schema are created afterwards
+// so class status is "migrating" until they are ready.
+func (ctr *Controller) ClassCreate(c echo.Context) error {
+	mgr := ctr.q.NewClassManager(c)
+	o := models.NewClass()
+
+	v := &validators.ClassForm{
+		ClassQ: mgr.Query((*models.Class)(nil)),
+		GroupQ: ctr.q.NewUserGroupManager(c).Q((*models.UserGroup)(nil)),
+	}
+
+	if err := api.BindValidateAndExec(c, v, func() error {
+		if err := validateClassName(v.Name); err != nil {
+			return err
+		}
+
+		if err := v.Bind(o); err != nil {
+			return err
+		}
+
+		if err := ctr.validateClassSchema(c, o, v.Schema); err != nil {
+			return err
+		}
+
+		if err := ctr.checkClassesLimit(c, mgr); err != nil {
+			return err
+		}
+
+		if err := setClassSchema(o, v.Schema); err != nil {
+			return err
+		}
+
+		return mgr.Insert(o)
+	}); err != nil {
+		return err
+	}
+
+	if o.IsLocked() {
+		ctr.startClassMigration(c, mgr.DB(), o)
+	}
+
+	return api.Render(c, http.StatusCreated, serializers.ClassSerializer{}.Response(o))
+}
+
+func validateClassName(name string) error {
+	_, reserved := classReservedNames[name]
+	if reserved || !classNameRegex.MatchString(name) {
+		return api.NewError(http.StatusBadRequest, map[string]interface{}{"name": []string{
+			"Invalid class name. Class name has to start with a letter and contain only lowercase letters, digits and underscores."}})
+	}
+
+	return nil
+}
+
+// checkClassesLimit returns error if instance already has as many classes as allowed by its plan.
+func (ctr *Controller) checkClassesLimit(c echo.Context, mgr *query.ClassManager) error {
+	sub := c.Get(contextSubscriptionKey).(*models.Subscription)
+
+	limit := c.Get(contextAdminLimitKey).(*models.AdminLimit).ClassesCount(sub)
+	if limit < 0 {
+		return nil
+	}
+
+	count, err := mgr.UserDefinedQ((*models.Class)(nil)).Count()
+	if err != nil {
+		return err
+	}
+
+	if count >= limit {
+		return api.NewGenericError(http.StatusForbidden, fmt.Sprintf("Classes count limit exceeded (%d).", limit))
+	}
+
+	return nil
+}
+
 func (ctr *Controller) ClassList(c echo.Context) error {
 	var o []*models.Class
 
@@ -47,7 +175,7 @@ func (ctr *Controller) ClassList(c echo.Context) error {
 		return err
 	}
 
-	return api.Render(c, http.StatusOK, serializers.CreatePage(c, r, nil))
+	return api.RenderWithWeakETag(c, http.StatusOK, serializers.CreatePage(c, r, nil))
 }
 
 func detailClass(c echo.Context) *models.Class {
@@ -55,9 +183,10 @@ func detailClass(c echo.Context) *models.Class {
 }
 
 func (ctr *Controller) ClassRetrieve(c echo.Context) error {
+	mgr := ctr.q.NewClassManager(c)
 	o := detailClass(c)
 
-	if err := ctr.q.NewClassManager(c).WithAccessByNameQ(o).Select(); err != nil {
+	if err := mgr.WithAccessByNameQ(o).Select(); err != nil {
 		if err == pg.ErrNoRows {
 			return api.NewNotFoundError(o)
 		}
@@ -65,41 +194,124 @@ func (ctr *Controller) ClassRetrieve(c echo.Context) error {
 		return err
 	}
 
-	return api.Render(c, http.StatusOK, serializers.ClassSerializer{}.Response(o))
+	return api.RenderWithETag(c, http.StatusOK, serializers.ClassSerializer{}.Response(o), api.ETag(o.Revision, o.UpdatedAt.Time))
 }
 
+// ClassUpdate updates class. Schema changes that affect indexes lock the class until indexes are migrated
+// in background. Updating locked class resumes its migration in case it was interrupted.
 func (ctr *Controller) ClassUpdate(c echo.Context) error {
-	// TODO: #8 Class updates/deletes
 	mgr := ctr.q.NewClassManager(c)
 	o := detailClass(c)
+	v := &validators.ClassUpdateForm{
+		GroupQ: ctr.q.NewUserGroupManager(c).Q((*models.UserGroup)(nil)),
+	}
 
-	if err := mgr.RunInTransaction(func(*pg.Tx) error {
-		err := manager.Lock(mgr.WithAccessByNameQ(o))
-		if err == pg.ErrNoRows {
-			return api.NewNotFoundError(o)
+	if err := api.BindAndValidate(c, v); err != nil {
+		return err
+	}
+
+	var locked bool
+
+	if err := mgr.RunInTransaction(func(tx *pg.Tx) error {
+		if err := manager.Lock(mgr.WithAccessByNameQ(o)); err != nil {
+			if err == pg.ErrNoRows {
+				return api.NewNotFoundError(o)
+			}
+
+			return err
 		}
 
-		return err
+		if err := api.CheckIfMatch(c, api.ETag(o.Revision, o.UpdatedAt.Time)); err != nil {
+			return err
+		}
+
+		if locked = o.IsLocked(); locked {
+			return newClassLockedError()
+		}
+
+		if err := v.Bind(o); err != nil {
+			return err
+		}
+
+		o.Revision++
+
+		if v.Schema != nil {
+			if err := ctr.validateClassSchema(c, o, *v.Schema); err != nil {
+				return err
+			}
+
+			if err := checkClassUniqueFields(tx, o, *v.Schema); err != nil {
+				return err
+			}
+
+			if err := setClassSchema(o, *v.Schema); err != nil {
+				return err
+			}
+		}
+
+		if err := mgr.Update(o, "description", "schema", "mapping", "refs", "index_changes", "migration_error", "metadata",
+			"group_id", "group_permissions", "other_permissions", "revision", "updated_at"); err != nil {
+			return err
+		}
+
+		if o.IsLocked() {
+			ctr.startClassMigration(c, tx, o)
+		}
+
+		return nil
 	}); err != nil {
+		// Resume migration in case it was interrupted.
+		if locked {
+			ctr.startClassMigration(c, mgr.DB(), o)
+		}
+
 		return err
 	}
 
-	return api.NewPermissionDeniedError()
+	return api.RenderWithETag(c, http.StatusOK, serializers.ClassSerializer{}.Response(o), api.ETag(o.Revision, o.UpdatedAt.Time))
 }
 
+// ClassDelete hides class and deletes it along with its data objects in background.
+// Deleting class that is already being deleted resumes its deletion.
 func (ctr *Controller) ClassDelete(c echo.Context) error {
-	// TODO: #8 Class updates/deletes
-	// index cleanup, DO cascade!
-	//
-	// user := detailUserObject(c)
-	// if user == nil {
-	// 	return api.NewNotFoundError(user)
-	// }
-	//
-	// mgr := query.NewUserMembershipManager(c)
-	// group := c.Get(contextUserGroupKey).(*models.UserGroup)
-	// o := &models.UserMembership{UserID: user.ID, GroupID: group.ID}
-	//
-	// return api.SimpleDelete(c, mgr, mgr.ForUserAndGroupQ(o), o)
-	return api.NewPermissionDeniedError()
+	mgr := ctr.q.NewClassManager(c)
+	o := detailClass(c)
+
+	if err := mgr.RunInTransaction(func(tx *pg.Tx) error {
+		if err := manager.Lock(mgr.ByNameQ(o)); err != nil {
+			if err == pg.ErrNoRows {
+				return api.NewNotFoundError(o)
+			}
+
+			return err
+		}
+
+		if o.Name == models.UserClassName {
+			return api.NewPermissionDeniedError()
+		}
+
+		if o.Visible {
+			if err := api.CheckIfMatch(c, api.ETag(o.Revision, o.UpdatedAt.Time)); err != nil {
+				return err
+			}
+
+			if o.IsLocked() {
+				return newClassLockedError()
+			}
+
+			o.Visible = false
+
+			if err := mgr.Update(o, "visible"); err != nil {
+				return err
+			}
+		}
+
+		ctr.startClassDeletion(c, tx, o)
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
 }
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/query"
+	"github.com/Syncano/orion/app/validators"
+	"github.com/Syncano/pkg-go/v2/database/manager"
+)
+
+const classDeleteChunkSize = 500
+
+// startClassDeletion deletes hidden class in background once current transaction is committed.
+func (ctr *Controller) startClassDeletion(c echo.Context, tx *pg.Tx, class *models.Class) {
+	ctr.startClassJob(c, tx, class, "deletion", ctr.deleteClass)
+}
+
+// deleteClass deletes data objects of class in chunks, drops its indexes, removes fields referencing it
+// from other classes and finally deletes class itself. Every step can be safely repeated so that interrupted
+// deletion can be resumed.
+func (ctr *Controller) deleteClass(c echo.Context, conn *pg.Conn, classID int) error {
+	mgr := ctr.q.NewClassManager(c)
+	class := &models.Class{ID: classID}
+
+	if err := mgr.ByIDQ(class).Select(); err != nil {
+		if err == pg.ErrNoRows {
+			return nil
+		}
+
+		return err
+	}
+
+	// Only classes hidden by delete request are deleted.
+	if class.Visible {
+		return nil
+	}
+
+	for {
+		n, err := ctr.deleteClassDataObjects(c, class)
+		if err != nil {
+			return err
+		}
+
+		if n < classDeleteChunkSize {
+			break
+		}
+	}
+
+	for kind, fieldsMap := range models.NewClassIndexes(class.ExistingIndexes.Get()) {
+		for _, mapping := range fieldsMap {
+			if err := dropClassIndex(conn, class.ID, kind, mapping); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, ch := range class.PendingIndexChanges() {
+		if err := dropClassIndex(conn, class.ID, ch.Kind, ch.Mapping); err != nil {
+			return err
+		}
+	}
+
+	if err := ctr.removeClassReferences(c, class); err != nil {
+		return err
+	}
+
+	if _, err := mgr.DB().Model(class).WherePK().Delete(); err != nil {
+		return err
+	}
+
+	ctr.c.ModelCacheInvalidate(mgr.DB(), class)
+
+	return nil
+}
+
+// deleteClassDataObjects deletes chunk of data objects of class along with their files and returns number
+// of deleted objects.
+func (ctr *Controller) deleteClassDataObjects(c echo.Context, class *models.Class) (int, error) {
+	var o []*models.DataObject
+
+	err := ctr.q.NewDataObjectManager(c).RunInTransaction(func(tx *pg.Tx) error {
+		if err := tx.Model(&o).Where("_klass_id = ?", class.ID).
+			OrderExpr("id").Limit(classDeleteChunkSize).For("UPDATE").Select(); err != nil || len(o) == 0 {
+			return err
+		}
+
+		for _, obj := range o {
+			if err := ctr.dataObjectDeleteHook(query.WrapContext(c), tx, obj); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Model(&o).WherePK().Delete()
+
+		return err
+	})
+
+	return len(o), err
+}
+
+// removeClassReferences removes reference and relation fields that target deleted class from other classes.
+// Classes that lose indexed fields are migrated afterwards.
+func (ctr *Controller) removeClassReferences(c echo.Context, class *models.Class) error {
+	var refs []*models.Class
+
+	mgr := ctr.q.NewClassManager(c)
+
+	if err := mgr.ReferencingQ(class, &refs).Select(); err != nil {
+		return err
+	}
+
+	for _, ref := range refs {
+		ref := ref
+
+		ok, err := ctr.withClassLock(c, ref.ID, func(*pg.Conn) error {
+			return ctr.removeClassReference(mgr, ref, class)
+		})
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("class %d referencing deleted class is locked", ref.ID)
+		}
+
+		if ref.IsLocked() {
+			ctr.startClassMigration(c, mgr.DB(), ref)
+		}
+	}
+
+	return nil
+}
+
+func (ctr *Controller) removeClassReference(mgr *query.ClassManager, ref, class *models.Class) error {
+	return mgr.RunInTransaction(func(*pg.Tx) error {
+		if err := manager.Lock(mgr.ByIDQ(ref)); err != nil {
+			return err
+		}
+
+		schema, err := classFieldForms(ref)
+		if err != nil {
+			return err
+		}
+
+		ref.Revision++
+
+		if err := setClassSchema(ref, classFieldsWithoutTarget(schema, class.Name)); err != nil {
+			return err
+		}
+
+		return mgr.Update(ref, "schema", "mapping", "refs", "index_changes", "migration_error", "revision", "updated_at")
+	})
+}
+
+// classFieldsWithoutTarget returns fields of schema that do not target class of specified name.
+func classFieldsWithoutTarget(schema []*validators.ClassFieldForm, name string) []*validators.ClassFieldForm {
+	kept := schema[:0]
+
+	for _, f := range schema {
+		if f.Target != name {
+			kept = append(kept, f)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/validators"
+)
+
+func TestClassFieldsWithoutTarget(t *testing.T) {
+	Convey("Given schema referencing deleted class", t, func() {
+		schema := []*validators.ClassFieldForm{
+			{Name: "a", Type: models.FieldStringType},
+			{Name: "b", Type: models.FieldReferenceType, Target: "posts"},
+			{Name: "c", Type: models.FieldRelationType, Target: "tags"},
+			{Name: "d", Type: models.FieldRelationType, Target: "posts"},
+		}
+
+		Convey("fields targeting deleted class are removed", func() {
+			kept := classFieldsWithoutTarget(schema, "posts")
+			So(kept, ShouldHaveLength, 2)
+			So(kept[0].Name, ShouldEqual, "a")
+			So(kept[1].Name, ShouldEqual, "c")
+		})
+		Convey("refs and index changes follow schema without removed fields", func() {
+			class := models.NewClass()
+			class.ID = 2
+			class.Name = "comments"
+
+			So(setClassSchema(class, []*validators.ClassFieldForm{
+				{Name: "post", Type: models.FieldReferenceType, Target: "posts", FilterIndex: true},
+				{Name: "tag", Type: models.FieldReferenceType, Target: "tags"},
+			}), ShouldBeNil)
+			So(class.ExistingIndexes.Set(class.Indexes()), ShouldBeNil)
+
+			schema, err := classFieldForms(class)
+			So(err, ShouldBeNil)
+			So(setClassSchema(class, classFieldsWithoutTarget(schema, "posts")), ShouldBeNil)
+
+			So(class.ComputedSchema(), ShouldNotContainKey, "post")
+			So(class.Refs.Get(), ShouldResemble, map[string]interface{}{"class": []interface{}{"tags"}})
+			So(class.PendingIndexChanges(), ShouldResemble, []*models.ClassIndexChange{
+				{Action: models.ClassIndexRemove, Kind: models.ClassIndexFilter, Field: "post", Mapping: "_post_1"},
+			})
+		})
+	})
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/query"
+	"github.com/Syncano/orion/app/settings"
+)
+
+const classLockKey = "%s.class.%d"
+
+// classJobContextKeys are copied from request context to context of class job that outlives it.
+var classJobContextKeys = []string{
+	settings.ContextInstanceKey,
+	settings.ContextSchemaKey,
+	contextSubscriptionKey,
+	contextAdminLimitKey,
+}
+
+// classJob is a background job of class run with connection holding class lock.
+type classJob func(c echo.Context, conn *pg.Conn, classID int) error
+
+// startClassJob runs job of class in background once current transaction is committed.
+// Job is skipped if there is another job of class already running.
+func (ctr *Controller) startClassJob(c echo.Context, db orm.DB, class *models.Class, name string, job classJob) {
+	jc := detachContext(c, classJobContextKeys...)
+	classID := class.ID
+
+	ctr.addDBCommitHook(c.Request().Context(), db, func() error {
+		go ctr.runClassJob(jc, classID, name, job)
+		return nil
+	})
+}
+
+func (ctr *Controller) runClassJob(c echo.Context, classID int, name string, job classJob) {
+	logger := ctr.log.Logger().With(zap.String("job", name), zap.Int("class", classID))
+
+	defer func() {
+		if r := recover(); r != nil {
+			logger.With(zap.Any("panic", r)).Error("Class job panicked")
+		}
+	}()
+
+	if _, err := ctr.withClassLock(c, classID, func(conn *pg.Conn) error {
+		return job(c, conn, classID)
+	}); err != nil {
+		logger.With(zap.Error(err)).Error("Class job failed")
+	}
+}
+
+// withClassLock runs fn with dedicated connection holding session advisory lock of class so that
+// only one job of class runs at a time. Returns false if lock is already held.
+func (ctr *Controller) withClassLock(c echo.Context, classID int, fn func(conn *pg.Conn) error) (bool, error) {
+	conn := query.TenantDB(c, ctr.db).Conn()
+	defer conn.Close()
+
+	lockKey := fmt.Sprintf(classLockKey, c.Get(settings.ContextSchemaKey), classID)
+
+	var locked bool
+
+	if _, err := conn.QueryOne(pg.Scan(&locked), "SELECT pg_try_advisory_lock(hashtext(?))", lockKey); err != nil || !locked {
+		return false, err
+	}
+
+	defer conn.Exec("SELECT pg_advisory_unlock(hashtext(?))", lockKey) // nolint: errcheck
+
+	return true, fn(conn)
+}
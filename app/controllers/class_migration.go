@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/pkg-go/v2/database/manager"
+)
+
+const (
+	classIndexTable             = "data_dataobject"
+	classIndexBtree             = "btree"
+	integrityViolationCodeClass = "23"
+	classUniqueFailedDetail     = "Unique constraint of field %q could not be applied due to duplicate values."
+)
+
+// classIndexMethods are all methods that class indexes may use.
+var classIndexMethods = []string{classIndexBtree, indexTypeGIN, indexTypeGIST}
+
+// classIndexDef is a database index backing class index.
+type classIndexDef struct {
+	method string
+	expr   string
+	unique bool
+}
+
+func newClassLockedError() *api.Error {
+	return api.NewGenericError(http.StatusConflict, "Class is being migrated, try again later.")
+}
+
+// classIndexDefs returns database indexes backing class index of specified kind for field.
+// Filter indexes use btree for comparisons along with index types hinted by lookups supported by field type.
+// Unique indexes are partial so that only data objects of the same class are compared.
+func classIndexDefs(kind string, field *models.DataObjectField) []*classIndexDef {
+	f := *field
+	f.TableAlias = classIndexTable
+	expr := f.SQLName()
+
+	switch kind {
+	case models.ClassIndexOrder:
+		return []*classIndexDef{{method: classIndexBtree, expr: expr}}
+	case models.ClassIndexUnique:
+		return []*classIndexDef{{method: classIndexBtree, expr: expr, unique: true}}
+	}
+
+	var defs []*classIndexDef
+
+	if classFieldIndexes[f.FType][models.ClassIndexOrder] {
+		defs = append(defs, &classIndexDef{method: classIndexBtree, expr: expr})
+	}
+
+	for _, method := range lookupIndexTypes(f.FType) {
+		def := &classIndexDef{method: method, expr: expr}
+
+		if method == indexTypeGIN && (f.FType == models.FieldStringType || f.FType == models.FieldTextType) {
+			def.expr = searchVectorSQL(&f)
+		}
+
+		defs = append(defs, def)
+	}
+
+	return defs
+}
+
+// startClassMigration runs migration of class in background once current transaction is committed.
+func (ctr *Controller) startClassMigration(c echo.Context, db orm.DB, class *models.Class) {
+	ctr.startClassJob(c, db, class, "migration", ctr.migrateClass)
+}
+
+// migrateClass builds and drops indexes of class one by one, saving progress after each of them so that
+// interrupted migration can be resumed.
+func (ctr *Controller) migrateClass(c echo.Context, conn *pg.Conn, classID int) error {
+	mgr := ctr.q.NewClassManager(c)
+
+	for {
+		class := &models.Class{ID: classID}
+		if err := mgr.ByIDQ(class).Select(); err != nil {
+			if err == pg.ErrNoRows {
+				return nil
+			}
+
+			return err
+		}
+
+		changes := class.PendingIndexChanges()
+		if len(changes) == 0 {
+			return nil
+		}
+
+		ch := changes[0]
+
+		if err := execClassIndexChange(conn, class, ch); err != nil {
+			if !isIntegrityViolation(err) || ch.Kind != models.ClassIndexUnique {
+				return err
+			}
+
+			// Duplicates are rejected by class update but data objects created concurrently with it may still
+			// prevent unique index from being built. Change is dropped then so that migration can proceed.
+			if err := ctr.failClassIndexChange(c, conn, class, ch); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := mgr.RunInTransaction(func(*pg.Tx) error {
+			if err := manager.Lock(mgr.ByIDQ(class)); err != nil {
+				return err
+			}
+
+			if err := class.CompleteIndexChange(ch); err != nil {
+				return err
+			}
+
+			return mgr.Update(class, "existing_indexes", "index_changes", "updated_at")
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// execClassIndexChange creates or drops database indexes of class index change. Indexes are always dropped first
+// as concurrent build that was interrupted leaves invalid index behind.
+func execClassIndexChange(conn *pg.Conn, class *models.Class, ch *models.ClassIndexChange) error {
+	if err := dropClassIndex(conn, class.ID, ch.Kind, ch.Mapping); err != nil {
+		return err
+	}
+
+	field, ok := class.ComputedSchema()[ch.Field]
+	if ch.Action != models.ClassIndexAdd || !ok || field.Mapping != ch.Mapping {
+		return nil
+	}
+
+	for _, def := range classIndexDefs(ch.Kind, field) {
+		createIndex := "CREATE INDEX"
+		if def.unique {
+			createIndex = "CREATE UNIQUE INDEX"
+		}
+
+		if _, err := conn.Exec(fmt.Sprintf("%s CONCURRENTLY %s ON ?schema.%s USING %s (%s) WHERE _klass_id = %d",
+			createIndex, models.ClassIndexName(class.ID, ch.Kind, def.method, ch.Mapping), classIndexTable, def.method, def.expr, class.ID)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropClassIndex drops all database indexes backing class index of field mapping.
+func dropClassIndex(conn *pg.Conn, classID int, kind, mapping string) error {
+	for _, method := range classIndexMethods {
+		if _, err := conn.Exec(fmt.Sprintf("DROP INDEX CONCURRENTLY IF EXISTS ?schema.%s",
+			models.ClassIndexName(classID, kind, method, mapping))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// failClassIndexChange drops unique index that failed to build and records failure on class. Schema is left intact
+// so that constraint is not silently removed but it is not enforced until field is updated without duplicates.
+func (ctr *Controller) failClassIndexChange(c echo.Context, conn *pg.Conn, class *models.Class, ch *models.ClassIndexChange) error {
+	if err := dropClassIndex(conn, class.ID, ch.Kind, ch.Mapping); err != nil {
+		return err
+	}
+
+	mgr := ctr.q.NewClassManager(c)
+
+	if err := mgr.RunInTransaction(func(*pg.Tx) error {
+		if err := manager.Lock(mgr.ByIDQ(class)); err != nil {
+			return err
+		}
+
+		if err := class.FailIndexChange(ch, fmt.Sprintf(classUniqueFailedDetail, ch.Field)); err != nil {
+			return err
+		}
+
+		return mgr.Update(class, "index_changes", "migration_error", "updated_at")
+	}); err != nil {
+		return err
+	}
+
+	ctr.log.Logger().With(zap.Int("class", class.ID), zap.String("field", ch.Field)).
+		Warn("Unique constraint not applied due to duplicate values")
+
+	return nil
+}
+
+// isIntegrityViolation returns true if err is a postgres integrity constraint violation.
+func isIntegrityViolation(err error) bool {
+	var pgErr pg.Error
+	return errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Field('C'), integrityViolationCodeClass)
+}
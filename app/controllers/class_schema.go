@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/labstack/echo/v4"
+	"github.com/mitchellh/mapstructure"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+	"github.com/Syncano/orion/app/validators"
+)
+
+const (
+	classTargetSelf = "self"
+	classTargetUser = "user"
+)
+
+// classNameRegex is a pattern for names of classes and their fields.
+var classNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// classReservedNames are names that cannot be used by user defined classes.
+var classReservedNames = map[string]struct{}{
+	classTargetSelf: {}, classTargetUser: {}, models.UserClassName: {},
+}
+
+// classFieldReservedNames are names used by fields common to all data objects and users.
+var classFieldReservedNames = map[string]struct{}{
+	"id": {}, "created_at": {}, "updated_at": {}, "revision": {},
+	"owner": {}, "owner_permissions": {}, "group": {}, "group_permissions": {}, "other_permissions": {},
+	"username": {}, "user_key": {}, "groups": {},
+	"channel": {}, "channel_room": {}, expectedRevisionKey: {},
+}
+
+// classFieldIndexes defines index kinds supported by field types.
+var classFieldIndexes = map[string]map[string]bool{
+	models.FieldStringType:    {models.ClassIndexFilter: true, models.ClassIndexOrder: true},
+	models.FieldTextType:      {models.ClassIndexFilter: true},
+	models.FieldIntegerType:   {models.ClassIndexFilter: true, models.ClassIndexOrder: true},
+	models.FieldFloatType:     {models.ClassIndexFilter: true, models.ClassIndexOrder: true},
+	models.FieldBooleanType:   {models.ClassIndexFilter: true, models.ClassIndexOrder: true},
+	models.FieldDatetimeType:  {models.ClassIndexFilter: true, models.ClassIndexOrder: true},
+	models.FieldFileType:      {},
+	models.FieldReferenceType: {models.ClassIndexFilter: true, models.ClassIndexOrder: true},
+	models.FieldRelationType:  {models.ClassIndexFilter: true},
+	models.FieldObjectType:    {models.ClassIndexFilter: true},
+	models.FieldArrayType:     {models.ClassIndexFilter: true},
+	models.FieldGeopointType:  {models.ClassIndexFilter: true},
+}
+
+// classFieldUniqueTypes are field types that support unique constraint.
+var classFieldUniqueTypes = map[string]struct{}{
+	models.FieldStringType:    {},
+	models.FieldIntegerType:   {},
+	models.FieldFloatType:     {},
+	models.FieldDatetimeType:  {},
+	models.FieldReferenceType: {},
+}
+
+func newSchemaError(format string, a ...interface{}) *api.Error {
+	return api.NewError(http.StatusBadRequest, map[string]interface{}{"schema": []string{fmt.Sprintf(format, a...)}})
+}
+
+// validateClassSchema validates field definitions of class schema.
+func (ctr *Controller) validateClassSchema(c echo.Context, class *models.Class, schema []*validators.ClassFieldForm) error {
+	if len(schema) > settings.API.ClassFieldsMax {
+		return newSchemaError("Too many fields defined (exceeds %d).", settings.API.ClassFieldsMax)
+	}
+
+	seen := make(map[string]struct{}, len(schema))
+
+	for _, f := range schema {
+		if _, ok := seen[f.Name]; ok {
+			return newSchemaError(`Duplicate field name: "%s".`, f.Name)
+		}
+
+		seen[f.Name] = struct{}{}
+
+		if err := ctr.validateClassField(c, class, f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ctr *Controller) validateClassField(c echo.Context, class *models.Class, f *validators.ClassFieldForm) error {
+	if !classNameRegex.MatchString(f.Name) {
+		return newSchemaError(`Invalid field name: "%s". Field name has to start with a letter and contain only lowercase letters, digits and underscores.`, f.Name)
+	}
+
+	if _, ok := classFieldReservedNames[f.Name]; ok {
+		return newSchemaError(`Field name "%s" is reserved.`, f.Name)
+	}
+
+	for kind, indexed := range map[string]bool{models.ClassIndexFilter: f.FilterIndex, models.ClassIndexOrder: f.OrderIndex} {
+		if indexed && !classFieldIndexes[f.Type][kind] {
+			return newSchemaError(`Field "%s" of type "%s" does not support %s index.`, f.Name, f.Type, kind)
+		}
+	}
+
+	if _, ok := classFieldUniqueTypes[f.Type]; f.Unique && !ok {
+		return newSchemaError(`Field "%s" of type "%s" does not support unique constraint.`, f.Name, f.Type)
+	}
+
+	if f.Language != "" {
+		if f.Type != models.FieldStringType && f.Type != models.FieldTextType {
+			return newSchemaError(`Field "%s" of type "%s" does not support language.`, f.Name, f.Type)
+		}
+
+		if _, ok := models.SearchLanguages[f.Language]; !ok {
+			return newSchemaError(`Unsupported language "%s" of field "%s".`, f.Language, f.Name)
+		}
+	}
+
+	// Indexes are identified by field mapping so language of search index cannot change in place.
+	if class.ID != 0 {
+		if o, ok := class.ComputedSchema()[f.Name]; ok && o.FType == f.Type && o.Language != f.Language {
+			return newSchemaError(`Language of field "%s" cannot be changed.`, f.Name)
+		}
+	}
+
+	if err := validateClassFieldConstraints(f); err != nil {
+		return err
+	}
+
+	return ctr.validateClassFieldTarget(c, class, f)
+}
+
+// validateClassFieldConstraints checks that value constraints are supported by field type
+// and that default value satisfies them.
+// nolint: gocyclo
+func validateClassFieldConstraints(f *validators.ClassFieldForm) error {
+	isString := f.Type == models.FieldStringType || f.Type == models.FieldTextType
+	isNumber := f.Type == models.FieldIntegerType || f.Type == models.FieldFloatType
+
+	if (f.Min != nil || f.Max != nil) && !isNumber {
+		return newSchemaError(`Field "%s" of type "%s" does not support min and max.`, f.Name, f.Type)
+	}
+
+	if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+		return newSchemaError(`Min of field "%s" cannot be greater than max.`, f.Name)
+	}
+
+	if f.MaxLength > 0 || f.Pattern != "" {
+		if !isString {
+			return newSchemaError(`Field "%s" of type "%s" does not support max_length and pattern.`, f.Name, f.Type)
+		}
+
+		maxLength := dataObjectStringMaxLength
+		if f.Type == models.FieldTextType {
+			maxLength = dataObjectTextMaxLength
+		}
+
+		if f.MaxLength > maxLength {
+			return newSchemaError(`Max length of field "%s" cannot exceed %d.`, f.Name, maxLength)
+		}
+
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			return newSchemaError(`Invalid pattern of field "%s".`, f.Name)
+		}
+	}
+
+	if f.Default == nil {
+		return nil
+	}
+
+	if f.Type == models.FieldFileType {
+		return newSchemaError(`Field "%s" of type "%s" does not support default.`, f.Name, f.Type)
+	}
+
+	field := f.Field()
+
+	val, err := dataObjectFieldValue(field, f.Default)
+	if err == nil {
+		err = checkFieldConstraints(field, val)
+	}
+
+	if err != nil {
+		return newSchemaError(`Invalid default of field "%s": %s`, f.Name, err)
+	}
+
+	return nil
+}
+
+// validateClassFieldTarget checks that reference and relation fields target existing class.
+func (ctr *Controller) validateClassFieldTarget(c echo.Context, class *models.Class, f *validators.ClassFieldForm) error {
+	if f.Type != models.FieldReferenceType && f.Type != models.FieldRelationType {
+		if f.Target != "" {
+			return newSchemaError(`Field "%s" of type "%s" does not support target.`, f.Name, f.Type)
+		}
+
+		return nil
+	}
+
+	switch f.Target {
+	case "":
+		return newSchemaError(`Field "%s" of type "%s" requires target.`, f.Name, f.Type)
+	case classTargetSelf, classTargetUser, class.Name:
+		return nil
+	}
+
+	target := &models.Class{Name: f.Target}
+	if err := ctr.q.NewClassManager(c).OneByName(target); err != nil || !target.Visible {
+		if err == nil || err == pg.ErrNoRows {
+			return newSchemaError(`Referenced class "%s" of field "%s" does not exist.`, f.Target, f.Name)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// classNewUniqueFields returns existing fields of class that are unique in schema but have no unique index yet,
+// including ones whose unique index failed to build. Fields that change type are mapped to new keys so they have no values yet.
+func classNewUniqueFields(class *models.Class, schema []*validators.ClassFieldForm) []*models.DataObjectField {
+	var ret []*models.DataObjectField
+
+	old := class.ComputedSchema()
+	existing := models.NewClassIndexes(class.ExistingIndexes.Get())
+
+	for _, f := range schema {
+		if o, ok := old[f.Name]; ok && f.Unique && existing[models.ClassIndexUnique][f.Name] != o.Mapping &&
+			o.FType == f.Type && o.Mapping != "" {
+			ret = append(ret, o)
+		}
+	}
+
+	return ret
+}
+
+// checkClassUniqueFields returns error if fields that become unique have duplicate values in data objects of class
+// as unique index cannot be built then.
+func checkClassUniqueFields(db orm.DB, class *models.Class, schema []*validators.ClassFieldForm) error {
+	for _, f := range classNewUniqueFields(class, schema) {
+		field := *f
+		field.TableAlias = dataObjectTableAlias
+
+		exists, err := db.Model((*models.DataObject)(nil)).
+			Where("_klass_id = ?", class.ID).
+			Where(fmt.Sprintf("%s IS NOT NULL", field.SQLName())).
+			GroupExpr(field.SQLName()).
+			Having("count(*) > 1").
+			Exists()
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return newSchemaError(`Field "%s" cannot be unique as data objects have duplicate values of it.`, f.FName)
+		}
+	}
+
+	return nil
+}
+
+// classFieldForms returns current schema of class as field definitions.
+func classFieldForms(class *models.Class) ([]*validators.ClassFieldForm, error) {
+	var ret []*validators.ClassFieldForm
+
+	schema, _ := class.Schema.Get().([]interface{})
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{TagName: "form", Result: &ret})
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, dec.Decode(schema)
+}
+
+// setClassSchema sets schema, mapping and refs of class. Fields that keep their name and type keep their mapping.
+// Other fields are mapped to keys unique for class revision so that they never point to data of removed fields.
+func setClassSchema(class *models.Class, schema []*validators.ClassFieldForm) error {
+	var old map[string]*models.DataObjectField
+
+	if class.ID != 0 {
+		old = class.ComputedSchema()
+	}
+
+	fieldsList := make([]map[string]interface{}, len(schema))
+	mapping := make(map[string]string, len(schema))
+	targets := make(map[string]struct{})
+
+	for i, f := range schema {
+		fieldsList[i] = f.Map()
+
+		if o, ok := old[f.Name]; ok && o.FType == f.Type && o.Mapping != "" {
+			mapping[f.Name] = o.Mapping
+		} else {
+			mapping[f.Name] = fmt.Sprintf("_%s_%d", f.Name, class.Revision)
+		}
+
+		switch f.Target {
+		case "", classTargetSelf, class.Name:
+		case classTargetUser:
+			targets[models.UserClassName] = struct{}{}
+		default:
+			targets[f.Target] = struct{}{}
+		}
+	}
+
+	if err := class.SetSchema(fieldsList, mapping); err != nil {
+		return err
+	}
+
+	refs := make([]string, 0, len(targets))
+	for name := range targets {
+		refs = append(refs, name)
+	}
+
+	sort.Strings(refs)
+
+	if err := class.Refs.Set(map[string]interface{}{"class": refs}); err != nil {
+		return err
+	}
+
+	return class.UpdateIndexChanges()
+}
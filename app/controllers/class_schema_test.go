@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+	"github.com/Syncano/orion/app/validators"
+)
+
+func TestValidateClassName(t *testing.T) {
+	Convey("Class names are validated", t, func() {
+		So(validateClassName("posts_2"), ShouldBeNil)
+
+		for _, name := range []string{"Posts", "2posts", "_posts", "po-sts", "self", "user", models.UserClassName} {
+			So(validateClassName(name), ShouldNotBeNil)
+		}
+	})
+}
+
+func TestValidateClassSchema(t *testing.T) {
+	Convey("Given class schema", t, func() {
+		ctr := &Controller{}
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		class := models.NewClass()
+		class.Name = "posts"
+		min, max := 5.0, 1.0
+
+		Convey("valid fields are accepted", func() {
+			So(ctr.validateClassSchema(c, class, []*validators.ClassFieldForm{
+				{Name: "title", Type: models.FieldStringType, FilterIndex: true, OrderIndex: true, Unique: true, MaxLength: 64},
+				{Name: "body", Type: models.FieldTextType, Language: "english", FilterIndex: true},
+				{Name: "rating", Type: models.FieldIntegerType, Max: &min, Default: 3.0},
+				{Name: "parent", Type: models.FieldReferenceType, Target: classTargetSelf},
+				{Name: "authors", Type: models.FieldRelationType, Target: classTargetUser},
+				{Name: "related", Type: models.FieldRelationType, Target: "posts"},
+			}), ShouldBeNil)
+		})
+		Convey("invalid fields are rejected", func() {
+			for _, f := range []*validators.ClassFieldForm{
+				{Name: "Title", Type: models.FieldStringType},
+				{Name: "id", Type: models.FieldStringType},
+				{Name: expectedRevisionKey, Type: models.FieldIntegerType},
+				{Name: "f", Type: models.FieldTextType, OrderIndex: true},
+				{Name: "f", Type: models.FieldFileType, FilterIndex: true},
+				{Name: "f", Type: models.FieldTextType, Unique: true},
+				{Name: "f", Type: models.FieldIntegerType, Language: "english"},
+				{Name: "f", Type: models.FieldStringType, Language: "klingon"},
+				{Name: "f", Type: models.FieldStringType, Min: &min},
+				{Name: "f", Type: models.FieldFloatType, Min: &min, Max: &max},
+				{Name: "f", Type: models.FieldIntegerType, MaxLength: 5},
+				{Name: "f", Type: models.FieldStringType, MaxLength: dataObjectStringMaxLength + 1},
+				{Name: "f", Type: models.FieldStringType, Pattern: "("},
+				{Name: "f", Type: models.FieldFileType, Default: "a"},
+				{Name: "f", Type: models.FieldIntegerType, Min: &min, Default: 1.0},
+				{Name: "f", Type: models.FieldStringType, Pattern: "^a", Default: "b"},
+				{Name: "f", Type: models.FieldReferenceType},
+				{Name: "f", Type: models.FieldStringType, Target: classTargetSelf},
+			} {
+				So(ctr.validateClassSchema(c, class, []*validators.ClassFieldForm{f}), ShouldNotBeNil)
+			}
+		})
+		Convey("duplicate field names are rejected", func() {
+			So(ctr.validateClassSchema(c, class, []*validators.ClassFieldForm{
+				{Name: "a", Type: models.FieldStringType},
+				{Name: "a", Type: models.FieldIntegerType},
+			}), ShouldNotBeNil)
+		})
+		Convey("number of fields is limited", func() {
+			schema := make([]*validators.ClassFieldForm, settings.API.ClassFieldsMax+1)
+			for i := range schema {
+				schema[i] = &validators.ClassFieldForm{Name: "f" + strings.Repeat("a", i), Type: models.FieldStringType}
+			}
+
+			So(ctr.validateClassSchema(c, class, schema), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestSetClassSchema(t *testing.T) {
+	Convey("Given new class", t, func() {
+		class := models.NewClass()
+		class.Name = "posts"
+
+		So(setClassSchema(class, []*validators.ClassFieldForm{
+			{Name: "title", Type: models.FieldStringType, FilterIndex: true},
+			{Name: "author", Type: models.FieldReferenceType, Target: classTargetUser},
+			{Name: "tags", Type: models.FieldReferenceType, Target: "tags"},
+			{Name: "parent", Type: models.FieldReferenceType, Target: classTargetSelf},
+		}), ShouldBeNil)
+
+		Convey("fields are mapped to keys of class revision", func() {
+			schema := class.ComputedSchema()
+			So(schema["title"].Mapping, ShouldEqual, "_title_1")
+			So(schema["author"].Mapping, ShouldEqual, "_author_1")
+		})
+		Convey("refs contain referenced classes other than class itself", func() {
+			So(class.Refs.Get(), ShouldResemble, map[string]interface{}{"class": []interface{}{"tags", models.UserClassName}})
+		})
+		Convey("indexes are scheduled and class is migrating", func() {
+			So(class.GetStatus(), ShouldEqual, "migrating")
+			So(class.PendingIndexChanges(), ShouldResemble, []*models.ClassIndexChange{
+				{Action: models.ClassIndexAdd, Kind: models.ClassIndexFilter, Field: "title", Mapping: "_title_1"},
+			})
+		})
+		Convey("fields that keep their type keep their mapping on update", func() {
+			class.ID = 1
+			class.Revision = 2
+
+			So(setClassSchema(class, []*validators.ClassFieldForm{
+				{Name: "title", Type: models.FieldStringType},
+				{Name: "author", Type: models.FieldIntegerType},
+			}), ShouldBeNil)
+
+			schema := class.ComputedSchema()
+			So(schema["title"].Mapping, ShouldEqual, "_title_1")
+			So(schema["author"].Mapping, ShouldEqual, "_author_2")
+		})
+	})
+}
+
+func TestClassNewUniqueFields(t *testing.T) {
+	Convey("Given class with schema", t, func() {
+		class := models.NewClass()
+		class.ID = 1
+		class.Name = "posts"
+
+		So(setClassSchema(class, []*validators.ClassFieldForm{
+			{Name: "a", Type: models.FieldStringType},
+			{Name: "b", Type: models.FieldStringType, Unique: true},
+			{Name: "c", Type: models.FieldStringType},
+		}), ShouldBeNil)
+
+		for _, ch := range class.PendingIndexChanges() {
+			So(class.CompleteIndexChange(ch), ShouldBeNil)
+		}
+
+		Convey("only existing fields that become unique need duplicate check", func() {
+			fields := classNewUniqueFields(class, []*validators.ClassFieldForm{
+				{Name: "a", Type: models.FieldStringType, Unique: true},
+				{Name: "b", Type: models.FieldStringType, Unique: true},
+				{Name: "c", Type: models.FieldIntegerType, Unique: true},
+				{Name: "d", Type: models.FieldStringType, Unique: true},
+			})
+
+			So(fields, ShouldHaveLength, 1)
+			So(fields[0].FName, ShouldEqual, "a")
+		})
+		Convey("unique field whose index failed to build needs duplicate check again", func() {
+			existing := models.NewClassIndexes(class.ExistingIndexes.Get())
+			delete(existing, models.ClassIndexUnique)
+			So(class.ExistingIndexes.Set(existing), ShouldBeNil)
+
+			fields := classNewUniqueFields(class, []*validators.ClassFieldForm{
+				{Name: "a", Type: models.FieldStringType},
+				{Name: "b", Type: models.FieldStringType, Unique: true},
+			})
+
+			So(fields, ShouldHaveLength, 1)
+			So(fields[0].FName, ShouldEqual, "b")
+		})
+	})
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+)
+
+func newTestAPIKey(options map[string]string) *models.APIKey {
+	o := &models.APIKey{Key: "key"}
+	So(o.Options.Set(options), ShouldBeNil)
+
+	return o
+}
+
+func TestClassPermissions(t *testing.T) {
+	Convey("Given class permissions", t, func() {
+		newContext := func(method string, keys map[string]interface{}) echo.Context {
+			c := echo.New().NewContext(httptest.NewRequest(method, "/", nil), httptest.NewRecorder())
+			for k, v := range keys {
+				c.Set(k, v)
+			}
+
+			return c
+		}
+		user := &models.User{ID: 1}
+		class := &models.Class{Name: "cls", OtherPermissions: models.ClassPermissionRead}
+
+		Convey("required permission depends on route", func() {
+			So(requiredClassPermission(newContext(http.MethodPost, nil)), ShouldEqual, models.ClassPermissionCreate)
+			So(requiredClassPermission(newContext(http.MethodGet, nil)), ShouldEqual, models.ClassPermissionList)
+
+			c := newContext(http.MethodGet, nil)
+			c.SetParamNames("object_id")
+			c.SetParamValues("1")
+			So(requiredClassPermission(c), ShouldEqual, models.ClassPermissionRead)
+		})
+		Convey("permissions apply to user key and opted in API keys", func() {
+			for _, tc := range []struct {
+				keys     map[string]interface{}
+				expected bool
+			}{
+				{map[string]interface{}{settings.ContextAPIKeyKey: newTestAPIKey(nil)}, false},
+				{map[string]interface{}{settings.ContextAPIKeyKey: newTestAPIKey(map[string]string{"class_permissions": "true"})}, true},
+				{map[string]interface{}{settings.ContextAPIKeyKey: newTestAPIKey(nil), settings.ContextUserKey: user}, true},
+				{map[string]interface{}{settings.ContextAPIKeyKey: newTestAPIKey(map[string]string{"ignore_acl": "true"}),
+					settings.ContextUserKey: user}, false},
+				{map[string]interface{}{settings.ContextAPIKeyKey: newTestAPIKey(map[string]string{"ignore_acl": "true", "class_permissions": "true"})}, false},
+				{map[string]interface{}{settings.ContextAdminKey: &models.Admin{}, settings.ContextUserKey: user}, false},
+			} {
+				So(classPermissionsApply(newContext(http.MethodGet, tc.keys)), ShouldEqual, tc.expected)
+			}
+		})
+		Convey("other permissions of class are checked against required one", func() {
+			c := newContext(http.MethodGet, map[string]interface{}{settings.ContextUserKey: user})
+
+			So(checkClassPermission(c, nil, class, models.ClassPermissionRead), ShouldBeNil)
+
+			err := checkClassPermission(c, nil, class, models.ClassPermissionList)
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Code, ShouldEqual, http.StatusForbidden)
+		})
+		Convey("requests not subject to permissions are allowed", func() {
+			c := newContext(http.MethodPost, map[string]interface{}{settings.ContextAPIKeyKey: newTestAPIKey(nil)})
+			So(checkClassPermission(c, nil, &models.Class{Name: "cls"}, models.ClassPermissionCreate), ShouldBeNil)
+		})
+		Convey("group permissions are not granted without class group", func() {
+			class.GroupPermissions = models.ClassPermissionCreate
+			c := newContext(http.MethodPost, map[string]interface{}{settings.ContextUserKey: user})
+
+			So(checkClassPermission(c, nil, class, models.ClassPermissionCreate), ShouldNotBeNil)
+		})
+	})
+}
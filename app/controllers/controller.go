@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"reflect"
 	"strings"
 
@@ -95,6 +96,17 @@ func (ctr *Controller) Redis() *rediscli.Redis {
 	return ctr.redis
 }
 
+// addDBCommitHook adds hook that is run after transaction of db is committed. Hooks of batch request
+// running in its own savepoint are collected by it instead, see endBatchSavepoint.
+func (ctr *Controller) addDBCommitHook(ctx context.Context, db orm.DB, f func() error) {
+	if hooks, ok := ctx.Value(batchHooksContextKey{}).(*batchHooks); ok {
+		*hooks = append(*hooks, f)
+		return
+	}
+
+	ctr.db.AddDBCommitHook(db, f)
+}
+
 func (ctr *Controller) cacheSaveHook(c database.DBContext, db orm.DB, created bool, m interface{}) error {
 	if created {
 		return nil
@@ -117,9 +129,11 @@ func (ctr *Controller) liveObjectSoftDeleteHook(c database.DBContext, db orm.DB,
 
 	objectPK := table.PKs[0].Value(reflect.ValueOf(m).Elem()).Interface()
 
-	ctr.db.AddDBCommitHook(db, func() error {
+	ec := c.Unwrap().(echo.Context)
+
+	ctr.addDBCommitHook(ec.Request().Context(), db, func() error {
 		return tasks.NewDeleteLiveObjectTask(
-			c.Unwrap().(echo.Context).Get(settings.ContextInstanceKey).(*models.Instance).ID,
+			ec.Get(settings.ContextInstanceKey).(*models.Instance).ID,
 			modelName, objectPK,
 		).Publish(ctr.cel)
 	})
@@ -22,11 +22,47 @@ import (
 )
 
 func (ctr *Controller) DataObjectCreate(c echo.Context) error {
-	// TODO: #16 Object updates
-	// o.Data.Set(map[string]string{ // nolint: errcheck
-	// 	"abc": "aa",
-	// })
-	return api.NewPermissionDeniedError()
+	class := c.Get(contextClassKey).(*models.Class)
+	o := models.NewDataObject(class)
+	setDataObjectOwner(c, o)
+
+	p, err := parseDataObjectPayload(c)
+	if err != nil {
+		return err
+	}
+
+	acl, err := popDataObjectACL(p)
+	if err != nil {
+		return err
+	}
+
+	if err := acl.validate(c, ctr.q); err != nil {
+		return err
+	}
+
+	acl.apply(o)
+
+	mgr := ctr.q.NewDataObjectManager(c)
+
+	if err := mgr.RunInTransaction(func(tx *pg.Tx) error {
+		if err := ctr.bindDataObject(c, tx, class, o, p); err != nil {
+			return err
+		}
+
+		if err := mgr.Insert(o); err != nil {
+			return err
+		}
+
+		ctr.launchDataObjectTrigger(c, tx, o, models.TriggerSignalCreate)
+
+		return nil
+	}); err != nil {
+		return uniqueViolationError(class, err)
+	}
+
+	serializer := serializers.DataObjectSerializer{Class: class}
+
+	return api.Render(c, http.StatusCreated, serializer.Response(o))
 }
 
 func (ctr *Controller) DataObjectList(c echo.Context) error {
@@ -39,13 +75,25 @@ func (ctr *Controller) DataObjectList(c echo.Context) error {
 	// Prepare query.
 	q := mgr.ForClassQ(class, &o)
 
+	orderFields := class.OrderFields()
+
 	if _, e := c.QueryParams()["query"]; e {
 		var err error
 
-		q, err = NewDataObjectQuery(class.FilterFields()).Parse(ctr.q, c, q)
+		doq := NewDataObjectQuery(class.FilterFields())
+
+		q, err = doq.Parse(ctr.q, c, q)
 		if err != nil {
 			return err
 		}
+
+		for name, f := range doq.OrderFields(class) {
+			orderFields[name] = f
+		}
+
+		if expr := doq.DistancesExpr(); expr != "" {
+			q = q.ColumnExpr("?TableAlias.*").ColumnExpr(expr + ` AS "distances"`)
+		}
 	}
 
 	// Check if include_count is defined, if so add count estimate.
@@ -59,7 +107,7 @@ func (ctr *Controller) DataObjectList(c echo.Context) error {
 	var paginator Paginator
 
 	if isValidOrderedPagination(c.QueryParam(orderByQuery)) {
-		paginator = &PaginatorOrderedDB{PaginatorDB: &PaginatorDB{Query: q}, OrderFields: class.OrderFields()}
+		paginator = &PaginatorOrderedDB{PaginatorDB: &PaginatorDB{Query: q}, OrderFields: orderFields}
 	} else {
 		paginator = &PaginatorDB{Query: q}
 	}
@@ -69,12 +117,23 @@ func (ctr *Controller) DataObjectList(c echo.Context) error {
 	// Return paginated results.
 	serializer := serializers.DataObjectSerializer{Class: class}
 
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
+		return err
+	}
+
+	serializer.Projection = projection
+
+	if serializer.Expansion, err = ctr.newExpansion(c, class); err != nil {
+		return err
+	}
+
 	r, err := Paginate(c, cursor, (*models.DataObject)(nil), serializer, paginator)
 	if err != nil {
 		return err
 	}
 
-	return api.Render(c, http.StatusOK, serializers.CreatePage(c, r, props))
+	return api.RenderWithWeakETag(c, http.StatusOK, serializers.CreatePage(c, r, props))
 }
 
 func detailDataObject(c echo.Context) *models.DataObject {
@@ -97,8 +156,20 @@ func (ctr *Controller) DataObjectRetrieve(c echo.Context) error {
 	}
 
 	class := c.Get(contextClassKey).(*models.Class)
+	serializer := serializers.DataObjectSerializer{Class: class}
+
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
+		return err
+	}
+
+	serializer.Projection = projection
+
+	if serializer.Expansion, err = ctr.newExpansion(c, class); err != nil {
+		return err
+	}
 
-	if err := ctr.q.NewDataObjectManager(c).ForClassByIDQ(class, o).Select(); err != nil {
+	if err = ctr.q.NewDataObjectManager(c).ForClassByIDQ(class, o).Select(); err != nil {
 		if err == pg.ErrNoRows {
 			return api.NewNotFoundError(o)
 		}
@@ -106,52 +177,95 @@ func (ctr *Controller) DataObjectRetrieve(c echo.Context) error {
 		return err
 	}
 
-	serializer := serializers.DataObjectSerializer{Class: class}
+	if err = serializer.Prepare([]interface{}{o}); err != nil {
+		return err
+	}
 
-	return api.Render(c, http.StatusOK, serializer.Response(o))
+	return api.RenderWithETag(c, http.StatusOK, serializer.Response(o), api.ETag(o.Revision, o.UpdatedAt.Time))
 }
 
 func (ctr *Controller) DataObjectUpdate(c echo.Context) error {
-	// TODO: #16 Object updates
-	mgr := ctr.q.NewDataObjectManager(c)
-
 	o := detailDataObject(c)
 	if o == nil {
 		return api.NewNotFoundError(o)
 	}
 
-	// Prepare virtual map.
-	class := c.Get(contextClassKey).(*models.Class)
-	schema := class.ComputedSchema()
-	virt := make(map[string]models.StateField)
+	p, err := parseDataObjectPayload(c)
+	if err != nil {
+		return err
+	}
 
-	for name, field := range schema {
-		virt[name] = field
+	expectedRevision, err := popExpectedRevision(p)
+	if err != nil {
+		return err
 	}
 
+	acl, err := popDataObjectACL(p)
+	if err != nil {
+		return err
+	}
+
+	if err := acl.validate(c, ctr.q); err != nil {
+		return err
+	}
+
+	class := c.Get(contextClassKey).(*models.Class)
+	mgr := ctr.q.NewDataObjectManager(c)
+	virt := dataObjectStateFields(class)
+
 	if err := mgr.RunInTransaction(func(tx *pg.Tx) error {
-		if err := manager.Lock(mgr.ForClassByIDQ(class, o)); err != nil {
+		if err := manager.Lock(mgr.WithPermissionQ(mgr.ForClassByIDQ(class, o), acl.requiredPermission())); err != nil {
 			if err == pg.ErrNoRows {
-				return api.NewNotFoundError(o)
+				return dataObjectPermissionError(mgr, class, o)
 			}
 
 			return err
 		}
+
+		if err := api.CheckIfMatch(c, api.ETag(o.Revision, o.UpdatedAt.Time)); err != nil {
+			return err
+		}
+
+		if expectedRevision != 0 && expectedRevision != o.Revision {
+			return api.NewRevisionConflictError(expectedRevision, o.Revision)
+		}
+
 		o.Snapshot(o, virt)
-		o.ID = 1
+
+		if err := ctr.bindDataObject(c, tx, class, o, p); err != nil {
+			return err
+		}
+
 		o.Snapshot(o, virt)
-		fmt.Println("changed", o.Changes())
-		fmt.Println("changed virt", o.ChangesVirtual())
 
-		// Update
-		// Validate size
-		// Snapshot and compute changes
+		columns := []string{"_data", "_files", "revision", "updated_at"}
+		aclChanged := acl.apply(o)
+
+		if aclChanged {
+			columns = append(columns, dataObjectACLColumns...)
+		}
+
+		// Skip update if nothing has changed.
+		if len(o.ChangesVirtual()) == 0 && !aclChanged {
+			return nil
+		}
+
+		o.Revision++
+
+		if err := mgr.Update(o, columns...); err != nil {
+			return err
+		}
+
+		ctr.launchDataObjectTrigger(c, tx, o, models.TriggerSignalUpdate)
+
 		return nil
 	}); err != nil {
-		return err
+		return uniqueViolationError(class, err)
 	}
 
-	return api.NewPermissionDeniedError()
+	serializer := serializers.DataObjectSerializer{Class: class}
+
+	return api.RenderWithETag(c, http.StatusOK, serializer.Response(o), api.ETag(o.Revision, o.UpdatedAt.Time))
 }
 
 func (ctr *Controller) DataObjectDelete(c echo.Context) error {
@@ -163,24 +277,53 @@ func (ctr *Controller) DataObjectDelete(c echo.Context) error {
 	class := c.Get(contextClassKey).(*models.Class)
 	mgr := ctr.q.NewDataObjectManager(c)
 
-	return api.SimpleDelete(c, mgr, mgr.ForClassByIDQ(class, o), o)
+	if err := mgr.RunInTransaction(func(*pg.Tx) error {
+		if err := manager.Lock(mgr.WithPermissionQ(mgr.ForClassByIDQ(class, o), models.PermissionFull)); err != nil {
+			if err == pg.ErrNoRows {
+				return dataObjectPermissionError(mgr, class, o)
+			}
+
+			return err
+		}
+
+		if err := api.CheckIfMatch(c, api.ETag(o.Revision, o.UpdatedAt.Time)); err != nil {
+			return err
+		}
+
+		return mgr.Delete(o)
+	}); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusNoContent)
 }
 
 func (ctr *Controller) dataObjectDeleteHook(c database.DBContext, db orm.DB, i interface{}) error {
 	o := i.(*models.DataObject)
 	sizeDiff := 0
+	keys := make([]string, 0, len(o.Files.Map))
 
 	for k, v := range o.Files.Map {
-		fname := o.Data.Map[k]
-		util.Must(
-			ctr.fs.Default().Delete(context.Background(), settings.BucketData, fname.String),
-		)
+		keys = append(keys, o.Data.Map[k].String)
 
 		if d, e := models.ValueFromString(models.FieldIntegerType, v.String); e == nil {
 			sizeDiff += d.(int)
 		}
 	}
 
+	// Files are deleted from storage only once deletion of object is committed.
+	if len(keys) > 0 {
+		ctr.addDBCommitHook(c.Unwrap().(echo.Context).Request().Context(), db, func() error {
+			for _, key := range keys {
+				if err := ctr.fs.Default().Delete(context.Background(), settings.BucketData, key); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
 	if sizeDiff != 0 {
 		sub := c.Unwrap().(echo.Context).Get(contextSubscriptionKey).(*models.Subscription)
 		c.Unwrap().(echo.Context).Get(contextAdminLimitKey).(*models.AdminLimit).StorageLimit(sub)
@@ -191,7 +334,8 @@ func (ctr *Controller) dataObjectDeleteHook(c database.DBContext, db orm.DB, i i
 	return nil
 }
 
-func (ctr *Controller) uploadDataObjectFile(ctx context.Context, db orm.DB, instance *models.Instance, class *models.Class, fh *multipart.FileHeader) error { // nolint - ignore that it is unused for now
+// uploadDataObjectFile uploads file to data bucket and returns its storage key.
+func (ctr *Controller) uploadDataObjectFile(ctx context.Context, db orm.DB, instance *models.Instance, class *models.Class, fh *multipart.FileHeader) (string, error) {
 	key := fmt.Sprintf("%s/%d/%s%s",
 		instance.StoragePrefix,
 		class.ID,
@@ -201,10 +345,10 @@ func (ctr *Controller) uploadDataObjectFile(ctx context.Context, db orm.DB, inst
 
 	f, err := fh.Open()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	defer f.Close()
 
-	return storage.SafeUpload(ctx, ctr.fs.Default(), ctr.db, db, settings.BucketData, key, f)
+	return key, storage.SafeUpload(ctx, ctr.fs.Default(), ctr.db, db, settings.BucketData, key, f)
 }
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/query"
+)
+
+const (
+	ownerPermissionsKey = "owner_permissions"
+	groupKey            = "group"
+	groupPermissionsKey = "group_permissions"
+	otherPermissionsKey = "other_permissions"
+)
+
+// dataObjectACLColumns are columns updated when ACL of data object changes.
+var dataObjectACLColumns = []string{"owner_permissions", "group_id", "group_permissions", "other_permissions"}
+
+// dataObjectACL is ACL defined in data object payload. Nil fields are not changed.
+type dataObjectACL struct {
+	ownerPermissions *int
+	groupPermissions *int
+	otherPermissions *int
+	group            *int
+}
+
+// popDataObjectACL removes ACL keys from payload and returns ACL defined by them or nil if there are none.
+func popDataObjectACL(p *dataObjectPayload) (*dataObjectACL, error) {
+	var found bool
+
+	acl := &dataObjectACL{}
+	errs := make(map[string]interface{})
+
+	for key, dst := range map[string]**int{
+		ownerPermissionsKey: &acl.ownerPermissions,
+		groupPermissionsKey: &acl.groupPermissions,
+		otherPermissionsKey: &acl.otherPermissions,
+	} {
+		v, ok := p.data[key]
+		if !ok {
+			continue
+		}
+
+		delete(p.data, key)
+
+		found = true
+		s, _ := v.(string)
+
+		perm, ok := models.PermissionLevels[s]
+		if !ok {
+			errs[key] = []string{`Invalid value, expected one of: "none", "read", "write", "full".`}
+			continue
+		}
+
+		*dst = &perm
+	}
+
+	if v, ok := p.data[groupKey]; ok {
+		delete(p.data, groupKey)
+
+		found = true
+
+		var group int
+
+		if s, ok := v.(string); ok {
+			group, _ = strconv.Atoi(s)
+		} else if f, ok := toFloat(v); ok {
+			group = int(f)
+		}
+
+		if v != nil && group <= 0 {
+			errs[groupKey] = []string{"Invalid value, expected group id."}
+		}
+
+		acl.group = &group
+	}
+
+	if len(errs) > 0 {
+		return nil, api.NewError(http.StatusBadRequest, errs)
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return acl, nil
+}
+
+// validate checks that group referenced by ACL exists.
+func (acl *dataObjectACL) validate(c echo.Context, qf *query.Factory) error {
+	if acl == nil || acl.group == nil || *acl.group == 0 {
+		return nil
+	}
+
+	exists, err := qf.NewUserGroupManager(c).ByIDQ(&models.UserGroup{ID: *acl.group}).Exists()
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return api.NewError(http.StatusBadRequest, map[string]interface{}{groupKey: []string{"Group does not exist."}})
+	}
+
+	return nil
+}
+
+// apply sets ACL of data object and returns true if it changed.
+func (acl *dataObjectACL) apply(o *models.DataObject) bool {
+	if acl == nil {
+		return false
+	}
+
+	changed := false
+
+	for _, f := range []struct {
+		src *int
+		dst *int
+	}{
+		{acl.ownerPermissions, &o.OwnerPermissions},
+		{acl.groupPermissions, &o.GroupPermissions},
+		{acl.otherPermissions, &o.OtherPermissions},
+		{acl.group, &o.GroupID},
+	} {
+		if f.src != nil && *f.src != *f.dst {
+			*f.dst = *f.src
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// requiredPermission returns permission needed to update data object. Changing ACL requires full permission.
+func (acl *dataObjectACL) requiredPermission() int {
+	if acl != nil {
+		return models.PermissionFull
+	}
+
+	return models.PermissionWrite
+}
+
+// dataObjectPermissionError returns error for data object that was not found with required permission.
+// Objects that are still readable result in permission denied error.
+func dataObjectPermissionError(mgr *query.DataObjectManager, class *models.Class, o *models.DataObject) error {
+	exists, err := mgr.ForClassByIDQ(class, &models.DataObject{ID: o.ID}).Exists()
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return api.NewPermissionDeniedError()
+	}
+
+	return api.NewNotFoundError(o)
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestPopDataObjectACL(t *testing.T) {
+	Convey("Given data object payload", t, func() {
+		Convey("payload without ACL keys has no ACL", func() {
+			p := &dataObjectPayload{data: map[string]interface{}{"a": 1.0}}
+			acl, err := popDataObjectACL(p)
+			So(err, ShouldBeNil)
+			So(acl, ShouldBeNil)
+			So(acl.requiredPermission(), ShouldEqual, models.PermissionWrite)
+		})
+		Convey("ACL keys are removed from payload", func() {
+			p := &dataObjectPayload{data: map[string]interface{}{
+				"a": 1.0, ownerPermissionsKey: "write", groupPermissionsKey: "read", otherPermissionsKey: "none", groupKey: 5.0,
+			}}
+			acl, err := popDataObjectACL(p)
+			So(err, ShouldBeNil)
+			So(p.data, ShouldResemble, map[string]interface{}{"a": 1.0})
+			So(*acl.ownerPermissions, ShouldEqual, models.PermissionWrite)
+			So(*acl.groupPermissions, ShouldEqual, models.PermissionRead)
+			So(*acl.otherPermissions, ShouldEqual, models.PermissionNone)
+			So(*acl.group, ShouldEqual, 5)
+			So(acl.requiredPermission(), ShouldEqual, models.PermissionFull)
+		})
+		Convey("group can be unset with null", func() {
+			acl, err := popDataObjectACL(&dataObjectPayload{data: map[string]interface{}{groupKey: nil}})
+			So(err, ShouldBeNil)
+			So(*acl.group, ShouldEqual, 0)
+		})
+		Convey("invalid values are rejected", func() {
+			_, err := popDataObjectACL(&dataObjectPayload{data: map[string]interface{}{
+				ownerPermissionsKey: "all", otherPermissionsKey: 1.0, groupKey: "abc",
+			}})
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Code, ShouldEqual, http.StatusBadRequest)
+			So(err.(*api.Error).Data, ShouldContainKey, ownerPermissionsKey)
+			So(err.(*api.Error).Data, ShouldContainKey, otherPermissionsKey)
+			So(err.(*api.Error).Data, ShouldContainKey, groupKey)
+		})
+	})
+}
+
+func TestDataObjectACLApply(t *testing.T) {
+	Convey("Given data object with default ACL", t, func() {
+		o := models.NewDataObject(&models.Class{ID: 1})
+
+		Convey("nil ACL does not change object", func() {
+			var acl *dataObjectACL
+
+			So(acl.apply(o), ShouldBeFalse)
+			So(acl.validate(nil, nil), ShouldBeNil)
+		})
+		Convey("ACL changes permissions and group", func() {
+			read, group := models.PermissionRead, 3
+			acl := &dataObjectACL{otherPermissions: &read, group: &group}
+
+			So(acl.apply(o), ShouldBeTrue)
+			So(o.OtherPermissions, ShouldEqual, models.PermissionRead)
+			So(o.GroupID, ShouldEqual, 3)
+			So(o.OwnerPermissions, ShouldEqual, models.PermissionFull)
+		})
+		Convey("ACL equal to current one is not a change", func() {
+			full := models.PermissionFull
+			acl := &dataObjectACL{ownerPermissions: &full}
+
+			So(acl.apply(o), ShouldBeFalse)
+		})
+		Convey("unsetting group does not require group validation", func() {
+			group := 0
+			acl := &dataObjectACL{group: &group}
+
+			So(acl.validate(nil, nil), ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+)
+
+const (
+	aggregateGroupByMax = 3
+	aggregateMetricsMax = 8
+	aggregateCount      = "count"
+)
+
+var (
+	aggregateGroupTypes = map[string]struct{}{
+		models.FieldStringType:    {},
+		models.FieldIntegerType:   {},
+		models.FieldFloatType:     {},
+		models.FieldBooleanType:   {},
+		models.FieldDatetimeType:  {},
+		models.FieldReferenceType: {},
+	}
+	// aggregateMetrics maps metric to field types it supports.
+	aggregateMetrics = map[string][]string{
+		aggregateCount: nil,
+		"sum":          {models.FieldIntegerType, models.FieldFloatType},
+		"avg":          {models.FieldIntegerType, models.FieldFloatType},
+		"min":          {models.FieldIntegerType, models.FieldFloatType, models.FieldDatetimeType},
+		"max":          {models.FieldIntegerType, models.FieldFloatType, models.FieldDatetimeType},
+	}
+)
+
+type aggregateRow struct {
+	Row string
+}
+
+func newAggregateError(key, detail string) *api.Error {
+	return api.NewError(http.StatusBadRequest, map[string]interface{}{key: detail})
+}
+
+// splitParam returns non-empty comma separated values of query param.
+func splitParam(c echo.Context, name string) []string {
+	var ret []string
+
+	for _, s := range strings.Split(c.QueryParam(name), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			ret = append(ret, s)
+		}
+	}
+
+	return ret
+}
+
+// aggregateGroupBy returns SQL expressions of group_by fields.
+func aggregateGroupBy(fields map[string]models.FilterField, names []string) ([]string, error) {
+	if len(names) > aggregateGroupByMax {
+		return nil, newAggregateError("group_by", fmt.Sprintf("Too many fields defined (exceeds %d).", aggregateGroupByMax))
+	}
+
+	exprs := make([]string, 0, len(names))
+
+	for _, name := range names {
+		f, ok := fields[name]
+		if !ok {
+			return nil, newAggregateError("group_by", fmt.Sprintf(`Invalid field name specified or missing filter index: "%s".`, name))
+		}
+
+		if _, ok := aggregateGroupTypes[f.Type()]; !ok {
+			return nil, newAggregateError("group_by", fmt.Sprintf(`Grouping by field of type "%s" is not supported.`, f.Type()))
+		}
+
+		exprs = append(exprs, f.SQLName())
+	}
+
+	return exprs, nil
+}
+
+// aggregateMetricExprs returns SQL expressions of metrics defined as "<metric>" or "<metric>:<field>".
+func aggregateMetricExprs(fields map[string]models.FilterField, metrics []string) ([]string, error) {
+	if len(metrics) > aggregateMetricsMax {
+		return nil, newAggregateError("metrics", fmt.Sprintf("Too many metrics defined (exceeds %d).", aggregateMetricsMax))
+	}
+
+	exprs := make([]string, 0, len(metrics))
+
+	for _, metric := range metrics {
+		parts := strings.SplitN(metric, ":", 2)
+		op := parts[0]
+
+		types, ok := aggregateMetrics[op]
+		if !ok {
+			return nil, newAggregateError("metrics", fmt.Sprintf(`Invalid metric specified: "%s".`, op))
+		}
+
+		if len(parts) == 1 {
+			if op != aggregateCount {
+				return nil, newAggregateError("metrics", fmt.Sprintf(`Metric "%s" requires a field.`, op))
+			}
+
+			exprs = append(exprs, "count(*)")
+
+			continue
+		}
+
+		f, ok := fields[parts[1]]
+		if !ok {
+			return nil, newAggregateError("metrics", fmt.Sprintf(`Invalid field name specified or missing filter index: "%s".`, parts[1]))
+		}
+
+		if types != nil && !containsString(types, f.Type()) {
+			return nil, newAggregateError("metrics", fmt.Sprintf(`Metric "%s" is not supported for field of type "%s".`, op, f.Type()))
+		}
+
+		exprs = append(exprs, fmt.Sprintf("%s(%s)", op, f.SQLName()))
+	}
+
+	return exprs, nil
+}
+
+func containsString(lst []string, s string) bool {
+	for _, v := range lst {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DataObjectAggregate returns metrics of data objects matching query grouped by fields as a compact table.
+func (ctr *Controller) DataObjectAggregate(c echo.Context) error {
+	class := c.Get(contextClassKey).(*models.Class)
+	fields := class.FilterFields()
+	groupBy := splitParam(c, "group_by")
+
+	metrics := splitParam(c, "metrics")
+	if len(metrics) == 0 {
+		metrics = []string{aggregateCount}
+	}
+
+	groupExprs, err := aggregateGroupBy(fields, groupBy)
+	if err != nil {
+		return err
+	}
+
+	metricExprs, err := aggregateMetricExprs(fields, metrics)
+	if err != nil {
+		return err
+	}
+
+	q, err := NewDataObjectQuery(fields).Parse(ctr.q, c, ctr.q.NewDataObjectManager(c).ForClassQ(class, (*models.DataObject)(nil)))
+	if err != nil {
+		return err
+	}
+
+	q = q.ColumnExpr(fmt.Sprintf("json_build_array(%s) AS row", strings.Join(append(groupExprs, metricExprs...), ", ")))
+
+	for _, expr := range groupExprs {
+		q = q.GroupExpr(expr).OrderExpr(expr)
+	}
+
+	var rows []*aggregateRow
+
+	if err = q.Limit(settings.API.DataObjectNestedQueryLimit).Select(&rows); err != nil {
+		return err
+	}
+
+	ret := make([]api.RawMessage, len(rows))
+	for i, r := range rows {
+		ret[i] = api.RawMessage(r.Row)
+	}
+
+	return api.Render(c, http.StatusOK, map[string]interface{}{
+		"columns": append(groupBy, metrics...),
+		"rows":    ret,
+	})
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestSplitParam(t *testing.T) {
+	Convey("Given comma separated query param", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/?group_by=a,+b,,c+&metrics=", nil)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		Convey("values are trimmed and empty ones skipped", func() {
+			So(splitParam(c, "group_by"), ShouldResemble, []string{"a", "b", "c"})
+			So(splitParam(c, "metrics"), ShouldBeNil)
+			So(splitParam(c, "missing"), ShouldBeNil)
+		})
+	})
+}
+
+func TestAggregate(t *testing.T) {
+	Convey("Given class fields", t, func() {
+		fields := testFilterFields(
+			&models.DataObjectField{FName: "s", FType: models.FieldStringType},
+			&models.DataObjectField{FName: "i", FType: models.FieldIntegerType},
+			&models.DataObjectField{FName: "d", FType: models.FieldDatetimeType},
+			&models.DataObjectField{FName: "arr", FType: models.FieldArrayType},
+		)
+
+		Convey("group_by returns SQL of fields", func() {
+			exprs, err := aggregateGroupBy(fields, []string{"s", "i"})
+			So(err, ShouldBeNil)
+			So(exprs, ShouldResemble, []string{fields["s"].SQLName(), fields["i"].SQLName()})
+		})
+		Convey("group_by rejects unknown and unsupported fields", func() {
+			_, err := aggregateGroupBy(fields, []string{"x"})
+			So(err, ShouldNotBeNil)
+			_, err = aggregateGroupBy(fields, []string{"arr"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("group_by rejects too many fields", func() {
+			_, err := aggregateGroupBy(fields, []string{"s", "i", "d", "s"})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("metrics return SQL of aggregate functions", func() {
+			exprs, err := aggregateMetricExprs(fields, []string{"count", "sum:i", "max:d", "count:s"})
+			So(err, ShouldBeNil)
+			So(exprs, ShouldResemble, []string{
+				"count(*)",
+				"sum(" + fields["i"].SQLName() + ")",
+				"max(" + fields["d"].SQLName() + ")",
+				"count(" + fields["s"].SQLName() + ")",
+			})
+		})
+		Convey("metrics reject invalid definitions", func() {
+			for _, metric := range []string{"median:i", "sum", "sum:s", "avg:d", "min:x"} {
+				_, err := aggregateMetricExprs(fields, []string{metric})
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("metrics reject too many definitions", func() {
+			metrics := make([]string, aggregateMetricsMax+1)
+			for i := range metrics {
+				metrics[i] = aggregateCount
+			}
+
+			_, err := aggregateMetricExprs(fields, metrics)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/query"
+	"github.com/Syncano/orion/app/settings"
+	"github.com/Syncano/pkg-go/v2/database/manager"
+)
+
+// lockDataObjectsByQuery locks data objects matching query param that current context has specified permission to,
+// up to bulk max. Should be run in transaction.
+func (ctr *Controller) lockDataObjectsByQuery(c echo.Context, mgr *query.DataObjectManager, class *models.Class, perm int) ([]*models.DataObject, error) {
+	var o []*models.DataObject
+
+	if c.QueryParam("query") == "" {
+		return nil, newQueryError("This field is required.")
+	}
+
+	q, err := NewDataObjectQuery(class.FilterFields()).Parse(ctr.q, c, mgr.WithPermissionQ(mgr.ForClassQ(class, &o), perm))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := manager.Lock(q.OrderExpr("?TableAlias.id").Limit(settings.API.DataObjectBulkMax)); err != nil && err != pg.ErrNoRows {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// DataObjectBulkDelete deletes data objects matching query. Objects are deleted one by one so that
+// delete hooks (file cleanup, storage indicator, triggers) run for each of them.
+func (ctr *Controller) DataObjectBulkDelete(c echo.Context) error {
+	class := c.Get(contextClassKey).(*models.Class)
+	mgr := ctr.q.NewDataObjectManager(c)
+
+	var count int
+
+	if err := mgr.RunInTransaction(func(*pg.Tx) error {
+		objs, err := ctr.lockDataObjectsByQuery(c, mgr, class, models.PermissionFull)
+		if err != nil {
+			return err
+		}
+
+		for _, o := range objs {
+			if err := mgr.Delete(o); err != nil {
+				return err
+			}
+		}
+
+		count = len(objs)
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return api.Render(c, http.StatusOK, map[string]int{"count": count})
+}
+
+// DataObjectBulkUpdate applies payload to all data objects matching query.
+func (ctr *Controller) DataObjectBulkUpdate(c echo.Context) error {
+	p, err := parseDataObjectPayload(c)
+	if err != nil {
+		return err
+	}
+
+	if len(p.files) > 0 {
+		return api.NewBadRequestError("Files are not supported in bulk update.")
+	}
+
+	if _, ok := p.data[expectedRevisionKey]; ok {
+		return api.NewError(http.StatusBadRequest, map[string]interface{}{expectedRevisionKey: "Not supported in bulk update."})
+	}
+
+	acl, err := popDataObjectACL(p)
+	if err != nil {
+		return err
+	}
+
+	if err := acl.validate(c, ctr.q); err != nil {
+		return err
+	}
+
+	class := c.Get(contextClassKey).(*models.Class)
+	mgr := ctr.q.NewDataObjectManager(c)
+	virt := dataObjectStateFields(class)
+
+	var count int
+
+	if err := mgr.RunInTransaction(func(tx *pg.Tx) error {
+		objs, err := ctr.lockDataObjectsByQuery(c, mgr, class, acl.requiredPermission())
+		if err != nil {
+			return err
+		}
+
+		for _, o := range objs {
+			o.Snapshot(o, virt)
+
+			if err := ctr.bindDataObject(c, tx, class, o, p); err != nil {
+				return err
+			}
+
+			o.Snapshot(o, virt)
+
+			columns := []string{"_data", "_files", "revision", "updated_at"}
+			aclChanged := acl.apply(o)
+
+			if aclChanged {
+				columns = append(columns, dataObjectACLColumns...)
+			}
+
+			if len(o.ChangesVirtual()) == 0 && !aclChanged {
+				continue
+			}
+
+			o.Revision++
+
+			if err := mgr.Update(o, columns...); err != nil {
+				return err
+			}
+
+			ctr.launchDataObjectTrigger(c, tx, o, models.TriggerSignalUpdate)
+			count++
+		}
+
+		return nil
+	}); err != nil {
+		return uniqueViolationError(class, err)
+	}
+
+	return api.Render(c, http.StatusOK, map[string]int{"count": count})
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestDataObjectBulk(t *testing.T) {
+	Convey("Given bulk request", t, func() {
+		ctr := &Controller{}
+		newContext := func(target, body string) echo.Context {
+			req := httptest.NewRequest(http.MethodPatch, target, strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+			return echo.New().NewContext(req, httptest.NewRecorder())
+		}
+
+		Convey("query param is required", func() {
+			_, err := ctr.lockDataObjectsByQuery(newContext("/", ""), nil, &models.Class{Name: "cls"}, models.PermissionFull)
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("update rejects expected revision", func() {
+			err := ctr.DataObjectBulkUpdate(newContext("/?query=x", `{"a": 2, "expected_revision": 1}`))
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("update rejects invalid ACL", func() {
+			err := ctr.DataObjectBulkUpdate(newContext("/?query=x", `{"other_permissions": "all"}`))
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Code, ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
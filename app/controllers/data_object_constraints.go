@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-pg/pg/v9/orm"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+)
+
+const (
+	dataObjectTableAlias = "data_object"
+	uniqueViolationCode  = "23505"
+)
+
+var (
+	errFieldRequired = newFieldError("This field is required.")
+	errFieldNull     = newFieldError("This field may not be null.")
+	errFieldUnique   = newFieldError("This field must be unique.")
+)
+
+// checkFieldConstraints validates converted value against constraints defined in field schema.
+func checkFieldConstraints(f *models.DataObjectField, val interface{}) error {
+	switch v := val.(type) {
+	case nil:
+		if f.Required {
+			return errFieldNull
+		}
+
+	case int:
+		return checkFieldRange(f, float64(v))
+
+	case float64:
+		return checkFieldRange(f, v)
+
+	case string:
+		if f.MaxLength > 0 && utf8.RuneCountInString(v) > f.MaxLength {
+			return newFieldError("Ensure this field has no more than %d characters.", f.MaxLength)
+		}
+
+		if re := f.PatternRegexp(); re != nil && !re.MatchString(v) {
+			return newFieldError(`Ensure this field matches pattern "%s".`, f.Pattern)
+		}
+	}
+
+	return nil
+}
+
+func checkFieldRange(f *models.DataObjectField, v float64) error {
+	if f.Min != nil && v < *f.Min {
+		return newFieldError("Ensure this value is greater than or equal to %v.", *f.Min)
+	}
+
+	if f.Max != nil && v > *f.Max {
+		return newFieldError("Ensure this value is less than or equal to %v.", *f.Max)
+	}
+
+	return nil
+}
+
+// checkUniqueFields returns error if values of unique fields are already used by other data objects of class.
+// Unique indexes still guard against concurrent writes.
+func checkUniqueFields(db orm.DB, class *models.Class, o *models.DataObject, values map[*models.DataObjectField]interface{}) error {
+	errs := make(map[string]interface{})
+
+	for f, val := range values {
+		if !f.Unique || val == nil {
+			continue
+		}
+
+		field := *f
+		field.TableAlias = dataObjectTableAlias
+
+		q := db.Model((*models.DataObject)(nil)).
+			Where("_klass_id = ?", class.ID).
+			Where(fmt.Sprintf("%s = ?", field.SQLName()), val)
+
+		if o.ID != 0 {
+			q = q.Where("id != ?", o.ID)
+		}
+
+		exists, err := q.Exists()
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			errs[f.FName] = []string{errFieldUnique.Error()}
+		}
+	}
+
+	if len(errs) > 0 {
+		return api.NewError(http.StatusBadRequest, errs)
+	}
+
+	return nil
+}
+
+// uniqueViolationError converts violation of unique index of class field to field error.
+// Other errors are returned as is.
+func uniqueViolationError(class *models.Class, err error) error {
+	var pgErr pg.Error
+	if !errors.As(err, &pgErr) || pgErr.Field('C') != uniqueViolationCode {
+		return err
+	}
+
+	constraint := pgErr.Field('n')
+
+	for name, f := range class.ComputedSchema() {
+		if f.Unique && constraint == models.ClassIndexName(class.ID, models.ClassIndexUnique, classIndexBtree, f.Mapping) {
+			return api.NewError(http.StatusBadRequest, map[string]interface{}{name: []string{errFieldUnique.Error()}})
+		}
+	}
+
+	return err
+}
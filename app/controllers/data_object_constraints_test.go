@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+)
+
+// testPGError is a postgres error with specified fields.
+type testPGError map[byte]string
+
+func (e testPGError) Error() string {
+	return e['M']
+}
+
+func (e testPGError) Field(f byte) string {
+	return e[f]
+}
+
+func (e testPGError) IntegrityViolation() bool {
+	return true
+}
+
+func TestCheckFieldConstraints(t *testing.T) {
+	Convey("Given field constraints", t, func() {
+		min, max := 1.0, 10.0
+		f := &models.DataObjectField{FName: "f", Min: &min, Max: &max, MaxLength: 3, Pattern: "^[a-z]+$"}
+
+		Convey("numbers are checked against range", func() {
+			So(checkFieldConstraints(f, 1), ShouldBeNil)
+			So(checkFieldConstraints(f, 10.0), ShouldBeNil)
+			So(checkFieldConstraints(f, 0), ShouldNotBeNil)
+			So(checkFieldConstraints(f, 10.5), ShouldNotBeNil)
+		})
+		Convey("strings are checked against max length in characters and pattern", func() {
+			So(checkFieldConstraints(f, "abc"), ShouldBeNil)
+			So(checkFieldConstraints(f, "abcd"), ShouldNotBeNil)
+			So(checkFieldConstraints(f, "ab1"), ShouldNotBeNil)
+
+			f.Pattern = ""
+			So(checkFieldConstraints(f, "żół"), ShouldBeNil)
+		})
+		Convey("null is rejected only for required fields", func() {
+			So(checkFieldConstraints(f, nil), ShouldBeNil)
+
+			f.Required = true
+			So(checkFieldConstraints(f, nil), ShouldEqual, errFieldNull)
+		})
+	})
+}
+
+func TestBindDataObjectConstraints(t *testing.T) {
+	Convey("Given class with constrained fields", t, func() {
+		ctr := &Controller{}
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+		c.Set(settings.ContextInstanceKey, &models.Instance{})
+
+		class := models.NewClass()
+		class.Name = "cls"
+		So(class.SetSchema([]map[string]interface{}{
+			{"name": "name", "type": models.FieldStringType, "required": true},
+			{"name": "count", "type": models.FieldIntegerType, "default": 5, "min": 0},
+		}, map[string]string{"name": "_name", "count": "_count"}), ShouldBeNil)
+
+		bind := func(o *models.DataObject, data map[string]interface{}) error {
+			return ctr.bindDataObject(c, nil, class, o, &dataObjectPayload{data: data})
+		}
+
+		Convey("new object gets defaults of missing fields", func() {
+			o := models.NewDataObject(class)
+			So(bind(o, map[string]interface{}{"name": "a"}), ShouldBeNil)
+			So(class.ComputedSchema()["count"].Get(o), ShouldEqual, 5)
+		})
+		Convey("new object requires required fields", func() {
+			err := bind(models.NewDataObject(class), map[string]interface{}{"count": 1.0})
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Data, ShouldResemble, map[string]interface{}{"name": []string{errFieldRequired.Error()}})
+		})
+		Convey("constraint errors are keyed by field", func() {
+			err := bind(models.NewDataObject(class), map[string]interface{}{"name": nil, "count": -1.0})
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Data, ShouldContainKey, "name")
+			So(err.(*api.Error).Data, ShouldContainKey, "count")
+		})
+		Convey("existing object does not need required fields on update", func() {
+			o := models.NewDataObject(class)
+			o.ID = 1
+			So(bind(o, map[string]interface{}{"count": 2.0}), ShouldBeNil)
+			So(class.ComputedSchema()["count"].Get(o), ShouldEqual, 2)
+		})
+	})
+}
+
+func TestUniqueViolationError(t *testing.T) {
+	Convey("Given class with unique field", t, func() {
+		class := models.NewClass()
+		class.ID = 3
+		class.Name = "cls"
+		So(class.SetSchema([]map[string]interface{}{
+			{"name": "code", "type": models.FieldStringType, "unique": true},
+		}, map[string]string{"code": "_code"}), ShouldBeNil)
+
+		Convey("violation of unique index of field is converted to field error", func() {
+			err := uniqueViolationError(class, testPGError{
+				'C': uniqueViolationCode,
+				'n': models.ClassIndexName(class.ID, models.ClassIndexUnique, classIndexBtree, "_code"),
+			})
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Code, ShouldEqual, http.StatusBadRequest)
+			So(err.(*api.Error).Data, ShouldResemble, map[string]interface{}{"code": []string{errFieldUnique.Error()}})
+		})
+		Convey("other errors are returned as is", func() {
+			other := testPGError{'C': uniqueViolationCode, 'n': "other_index"}
+			So(uniqueViolationError(class, other), ShouldResemble, other)
+
+			err := errors.New("error")
+			So(uniqueViolationError(class, err), ShouldEqual, err)
+		})
+	})
+}
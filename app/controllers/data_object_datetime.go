@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/jinzhu/now"
+)
+
+const dateLayout = "2006-01-02"
+
+// datetimeParts maps datetime part lookups to SQL EXTRACT fields and their valid ranges.
+var datetimeParts = map[string]struct {
+	sqlField string
+	min, max int
+}{
+	"_month":    {"MONTH", 1, 12},
+	"_day":      {"DAY", 1, 31},
+	"_week_day": {"ISODOW", 1, 7},
+}
+
+// datetimeRange is a [From, To) range or [From, To] if it is inclusive.
+type datetimeRange struct {
+	From, To  time.Time
+	Inclusive bool
+}
+
+type datetimePart struct {
+	Value    int
+	Timezone string
+}
+
+// parseTimezoneValue unpacks lookup value that is either a plain value or {"value": ..., "timezone": ...}.
+// Timezone defaults to UTC.
+func parseTimezoneValue(val interface{}) (interface{}, *time.Location, bool) {
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return val, time.UTC, true
+	}
+
+	v, ok := m["value"]
+	if !ok || len(m) > 2 {
+		return nil, nil, false
+	}
+
+	tz, ok := m["timezone"]
+	if !ok {
+		return v, time.UTC, len(m) == 1
+	}
+
+	name, ok := tz.(string)
+	if !ok || name == "" {
+		return nil, nil, false
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	return v, loc, true
+}
+
+// toLookupInt returns integer of lookup value if it is a whole number within range.
+func toLookupInt(val interface{}, min, max int) (int, bool) {
+	f, ok := val.(float64)
+	if !ok || f != float64(int(f)) || int(f) < min || int(f) > max {
+		return 0, false
+	}
+
+	return int(f), true
+}
+
+func validateDateLookup(val interface{}) interface{} {
+	v, loc, ok := parseTimezoneValue(val)
+	if !ok {
+		return nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil
+	}
+
+	t, err := time.ParseInLocation(dateLayout, s, loc)
+	if err != nil {
+		return nil
+	}
+
+	return &datetimeRange{From: t, To: t.AddDate(0, 0, 1)}
+}
+
+func validateYearLookup(val interface{}) interface{} {
+	v, loc, ok := parseTimezoneValue(val)
+	if !ok {
+		return nil
+	}
+
+	year, ok := toLookupInt(v, 1, 9999)
+	if !ok {
+		return nil
+	}
+
+	t := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+
+	return &datetimeRange{From: t, To: t.AddDate(1, 0, 0)}
+}
+
+func validatePartLookup(lookup string, val interface{}) interface{} {
+	v, loc, ok := parseTimezoneValue(val)
+	if !ok {
+		return nil
+	}
+
+	part := datetimeParts[lookup]
+
+	i, ok := toLookupInt(v, part.min, part.max)
+	if !ok {
+		return nil
+	}
+
+	return &datetimePart{Value: i, Timezone: loc.String()}
+}
+
+// validateBetweenLookup returns inclusive range of [from, to] datetimes.
+func validateBetweenLookup(val interface{}) interface{} {
+	v, loc, ok := parseTimezoneValue(val)
+	if !ok {
+		return nil
+	}
+
+	lst, ok := v.([]interface{})
+	if !ok || len(lst) != 2 {
+		return nil
+	}
+
+	var bounds [2]time.Time
+
+	for i, item := range lst {
+		s, ok := item.(string)
+		if !ok {
+			return nil
+		}
+
+		t, err := now.ParseInLocation(loc, s)
+		if err != nil {
+			return nil
+		}
+
+		bounds[i] = t
+	}
+
+	if bounds[0].After(bounds[1]) {
+		return nil
+	}
+
+	return &datetimeRange{From: bounds[0], To: bounds[1], Inclusive: true}
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestDatetimeLookupValidation(t *testing.T) {
+	Convey("Given datetime lookup values", t, func() {
+		warsaw, err := time.LoadLocation("Europe/Warsaw")
+		So(err, ShouldBeNil)
+
+		Convey("timezone defaults to UTC", func() {
+			v, loc, ok := parseTimezoneValue(3.0)
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 3.0)
+			So(loc, ShouldEqual, time.UTC)
+		})
+		Convey("timezone is parsed from value dict", func() {
+			v, loc, ok := parseTimezoneValue(map[string]interface{}{"value": 3.0, "timezone": "Europe/Warsaw"})
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 3.0)
+			So(loc.String(), ShouldEqual, "Europe/Warsaw")
+		})
+		Convey("invalid value dicts are rejected", func() {
+			for _, val := range []map[string]interface{}{
+				{"timezone": "UTC"},
+				{"value": 3.0, "timezone": "Mars/Olympus"},
+				{"value": 3.0, "timezone": ""},
+				{"value": 3.0, "zone": "UTC"},
+				{"value": 3.0, "timezone": "UTC", "extra": 1},
+			} {
+				_, _, ok := parseTimezoneValue(val)
+				So(ok, ShouldBeFalse)
+			}
+		})
+		Convey("_date is a day long range in timezone", func() {
+			r := validateDateLookup(map[string]interface{}{"value": "2020-03-29", "timezone": "Europe/Warsaw"}).(*datetimeRange)
+			So(r.From.Equal(time.Date(2020, 3, 29, 0, 0, 0, 0, warsaw)), ShouldBeTrue)
+			So(r.To.Equal(time.Date(2020, 3, 30, 0, 0, 0, 0, warsaw)), ShouldBeTrue)
+			So(r.Inclusive, ShouldBeFalse)
+			So(validateDateLookup("2020-13-01"), ShouldBeNil)
+		})
+		Convey("_year is a year long range", func() {
+			r := validateYearLookup(2020.0).(*datetimeRange)
+			So(r.From.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)), ShouldBeTrue)
+			So(r.To.Equal(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)), ShouldBeTrue)
+			So(validateYearLookup(2020.5), ShouldBeNil)
+			So(validateYearLookup(0.0), ShouldBeNil)
+		})
+		Convey("datetime parts are validated against their range", func() {
+			p := validatePartLookup("_month", map[string]interface{}{"value": 12.0, "timezone": "Europe/Warsaw"}).(*datetimePart)
+			So(p, ShouldResemble, &datetimePart{Value: 12, Timezone: "Europe/Warsaw"})
+			So(validatePartLookup("_month", 13.0), ShouldBeNil)
+			So(validatePartLookup("_day", 0.0), ShouldBeNil)
+			So(validatePartLookup("_week_day", 7.0), ShouldNotBeNil)
+			So(validatePartLookup("_week_day", 8.0), ShouldBeNil)
+		})
+		Convey("_between is an inclusive range of ordered bounds", func() {
+			r := validateBetweenLookup([]interface{}{"2020-01-01", "2020-01-31 12:00"}).(*datetimeRange)
+			So(r.From.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)), ShouldBeTrue)
+			So(r.To.Equal(time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC)), ShouldBeTrue)
+			So(r.Inclusive, ShouldBeTrue)
+			So(validateBetweenLookup([]interface{}{"2020-01-31", "2020-01-01"}), ShouldBeNil)
+			So(validateBetweenLookup([]interface{}{"2020-01-01"}), ShouldBeNil)
+			So(validateBetweenLookup([]interface{}{"2020-01-01", 5.0}), ShouldBeNil)
+		})
+	})
+}
+
+func TestDatetimeLookups(t *testing.T) {
+	Convey("Given datetime field", t, func() {
+		doq := NewDataObjectQuery(testFilterFields(
+			&models.DataObjectField{FName: "d", FType: models.FieldDatetimeType},
+			&models.DataObjectField{FName: "s", FType: models.FieldStringType},
+		))
+
+		Convey("_date filters by half-open range", func() {
+			sql, err := testQuerySQL(doq, `{"d": {"_date": "2020-03-01"}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_d') >= '2020-03-01 00:00:00`)
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_d') < '2020-03-02 00:00:00`)
+		})
+		Convey("_between filters by inclusive range", func() {
+			sql, err := testQuerySQL(doq, `{"d": {"_between": ["2020-03-01", "2020-03-05"]}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_d') <= '2020-03-05 00:00:00`)
+		})
+		Convey("datetime parts are extracted in local time of timezone", func() {
+			sql, err := testQuerySQL(doq, `{"d": {"_month": {"value": 3, "timezone": "Europe/Warsaw"}}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `EXTRACT(MONTH FROM `)
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_d') AT TIME ZONE 'Europe/Warsaw') = 3`)
+
+			sql, err = testQuerySQL(doq, `{"d": {"_week_day": 1}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `AT TIME ZONE 'UTC') = 1`)
+			So(sql, ShouldContainSubstring, `EXTRACT(ISODOW FROM `)
+		})
+		Convey("invalid values are rejected", func() {
+			_, err := testQuerySQL(doq, `{"d": {"_month": 13}}`)
+			So(err, ShouldNotBeNil)
+			_, err = testQuerySQL(doq, `{"d": {"_date": true}}`)
+			So(err, ShouldNotBeNil)
+			_, err = testQuerySQL(doq, `{"d": {"_year": {"value": 2020, "timezone": "Nowhere"}}}`)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("lookups are not supported for non datetime fields", func() {
+			_, err := testQuerySQL(doq, `{"s": {"_year": 2020}}`)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("default datetime fields support lookups", func() {
+			class := models.NewClass()
+			class.Name = "cls"
+			So(class.SetSchema([]map[string]interface{}{}, nil), ShouldBeNil)
+
+			fields := class.FilterFields()
+			So(fields, ShouldContainKey, "created_at")
+
+			_, err := testQuerySQL(NewDataObjectQuery(fields), `{"created_at": {"_year": 2020}, "updated_at": {"_day": 1}}`)
+			So(err, ShouldBeNil)
+		})
+	})
+}
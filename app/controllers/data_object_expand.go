@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/serializers"
+	"github.com/Syncano/orion/app/settings"
+)
+
+const expandQuery = "expand"
+
+// expandTree maps expanded field names to expansions of their targets, e.g. "post.author" is {"post": {"author": {}}}.
+type expandTree map[string]expandTree
+
+func newExpandError(detail string) *api.Error {
+	return api.NewError(http.StatusBadRequest, map[string]interface{}{expandQuery: detail})
+}
+
+// newExpansion returns expansion of reference and relation fields defined by expand query param.
+// Returns nil if expand is not defined.
+func (ctr *Controller) newExpansion(c echo.Context, class *models.Class) (*serializers.Expansion, error) {
+	paths := splitParam(c, expandQuery)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	tree := make(expandTree)
+
+	for _, path := range paths {
+		node := tree
+
+		for _, name := range strings.Split(path, ".") {
+			if node[name] == nil {
+				node[name] = make(expandTree)
+			}
+
+			node = node[name]
+		}
+	}
+
+	var queries int
+
+	return ctr.buildExpansion(c, class, tree, &queries)
+}
+
+// buildExpansion validates expanded fields of class and read permission to their target classes.
+// Every expanded field costs one query so their total is limited by nested queries max, same as for "_is" lookups.
+func (ctr *Controller) buildExpansion(c echo.Context, class *models.Class, tree expandTree, queries *int) (*serializers.Expansion, error) {
+	schema := class.ComputedSchema()
+	targets := make(map[string]*models.Class)
+	subs := make(map[string]*serializers.Expansion)
+	e := &serializers.Expansion{MaxItems: settings.API.DataObjectNestedQueryLimit}
+
+	for name, sub := range tree {
+		f, ok := schema[name]
+		if !ok || (f.FType != models.FieldReferenceType && f.FType != models.FieldRelationType) {
+			return nil, newExpandError(fmt.Sprintf(`Invalid field name specified, expected reference or relation: "%s".`, name))
+		}
+
+		*queries++
+		if *queries > settings.API.DataObjectNestedQueriesMax {
+			return nil, newExpandError(fmt.Sprintf("Too many expanded fields defined (exceeds %d).", settings.API.DataObjectNestedQueriesMax))
+		}
+
+		target, err := ctr.expansionTarget(c, class, f)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = checkClassPermission(c, ctr.q, target, models.ClassPermissionRead); err != nil {
+			return nil, err
+		}
+
+		if len(sub) > 0 {
+			if subs[name], err = ctr.buildExpansion(c, target, sub, queries); err != nil {
+				return nil, err
+			}
+		}
+
+		targets[name] = target
+		e.Fields = append(e.Fields, f)
+	}
+
+	e.Load = func(f *models.DataObjectField, ids []int) (map[int]interface{}, error) {
+		return ctr.loadExpansion(c, targets[f.FName], subs[f.FName], ids)
+	}
+
+	return e, nil
+}
+
+func (ctr *Controller) expansionTarget(c echo.Context, class *models.Class, f *models.DataObjectField) (*models.Class, error) {
+	if f.Target == "self" {
+		return class, nil
+	}
+
+	target := &models.Class{Name: f.Target}
+	if err := ctr.q.NewClassManager(c).OneByName(target); err != nil {
+		if err == pg.ErrNoRows {
+			return nil, newExpandError(fmt.Sprintf(`Referenced class "%s" does not exist.`, f.Target))
+		}
+
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// loadExpansion loads objects of target class with one query and returns them serialized mapped by id.
+func (ctr *Controller) loadExpansion(c echo.Context, class *models.Class, sub *serializers.Expansion, ids []int) (map[int]interface{}, error) {
+	var (
+		objs       []interface{}
+		serializer serializers.Serializer
+	)
+
+	if class.Name == models.UserClassName {
+		var users []*models.User
+
+		if err := ctr.q.NewUserManager(c).Q(class, &users).Where("?TableAlias.id IN (?)", pg.In(ids)).Select(); err != nil {
+			return nil, err
+		}
+
+		for _, o := range users {
+			objs = append(objs, o)
+		}
+
+		serializer = serializers.UserSerializer{Class: class, Expansion: sub}
+	} else {
+		var dataObjects []*models.DataObject
+
+		if err := ctr.q.NewDataObjectManager(c).ForClassQ(class, &dataObjects).Where("?TableAlias.id IN (?)", pg.In(ids)).Select(); err != nil {
+			return nil, err
+		}
+
+		for _, o := range dataObjects {
+			objs = append(objs, o)
+		}
+
+		serializer = serializers.DataObjectSerializer{Class: class, Expansion: sub}
+	}
+
+	if err := serializer.(serializers.Preparer).Prepare(objs); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[int]interface{}, len(objs))
+
+	for _, o := range objs {
+		r := serializer.Response(o).(map[string]interface{})
+		ret[r["id"].(int)] = r
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+)
+
+func TestNewExpansion(t *testing.T) {
+	Convey("Given class with self references", t, func() {
+		ctr := &Controller{}
+		class := models.NewClass()
+		class.Name = "cls"
+		schema := []map[string]interface{}{
+			{"name": "name", "type": models.FieldStringType},
+			{"name": "parent", "type": models.FieldReferenceType, "target": "self"},
+			{"name": "children", "type": models.FieldRelationType, "target": "self"},
+		}
+		mapping := make(map[string]string)
+
+		for _, f := range schema {
+			mapping[f["name"].(string)] = "_" + f["name"].(string)
+		}
+
+		So(class.SetSchema(schema, mapping), ShouldBeNil)
+
+		newContext := func(query string) echo.Context {
+			req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+			return echo.New().NewContext(req, httptest.NewRecorder())
+		}
+
+		Convey("no expand returns nil expansion", func() {
+			e, err := ctr.newExpansion(newContext(""), class)
+			So(err, ShouldBeNil)
+			So(e, ShouldBeNil)
+		})
+		Convey("reference and relation fields are expanded", func() {
+			e, err := ctr.newExpansion(newContext("expand=parent,children"), class)
+			So(err, ShouldBeNil)
+			So(e.Fields, ShouldHaveLength, 2)
+			So(e.MaxItems, ShouldEqual, settings.API.DataObjectNestedQueryLimit)
+		})
+		Convey("nested paths are expanded once per field", func() {
+			e, err := ctr.newExpansion(newContext("expand=parent.children,parent"), class)
+			So(err, ShouldBeNil)
+			So(e.Fields, ShouldHaveLength, 1)
+		})
+		Convey("unknown and non reference fields are rejected", func() {
+			_, err := ctr.newExpansion(newContext("expand=unknown"), class)
+			So(err, ShouldNotBeNil)
+			_, err = ctr.newExpansion(newContext("expand=name"), class)
+			So(err, ShouldNotBeNil)
+			_, err = ctr.newExpansion(newContext("expand=parent.name"), class)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("number of expanded fields is limited by nested queries max", func() {
+			_, err := ctr.newExpansion(newContext("expand=parent.children.parent.children.parent"), class)
+			So(err, ShouldNotBeNil)
+			_, err = ctr.newExpansion(newContext("expand=parent.children.parent.children"), class)
+			So(err, ShouldBeNil)
+		})
+	})
+}
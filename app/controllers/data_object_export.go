@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-pg/pg/v9"
+	json "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/query"
+	"github.com/Syncano/orion/app/serializers"
+	"github.com/Syncano/pkg-go/v2/util"
+)
+
+const (
+	exportFormatQuery  = "format"
+	exportFormatNDJSON = "ndjson"
+	exportFormatCSV    = "csv"
+	exportCursorName   = "data_object_export"
+	exportFetchSize    = 500
+)
+
+var exportContentTypes = map[string]string{
+	exportFormatNDJSON: "application/x-ndjson",
+	exportFormatCSV:    "text/csv; charset=UTF-8",
+}
+
+func newFormatError() *api.Error {
+	return api.NewError(http.StatusBadRequest, map[string]interface{}{
+		exportFormatQuery: fmt.Sprintf(`Invalid format specified, expected "%s" or "%s".`, exportFormatNDJSON, exportFormatCSV)})
+}
+
+// exportWriter writes serialized objects in export format.
+type exportWriter interface {
+	Write(m map[string]interface{}) error
+	Flush() error
+}
+
+type ndjsonExportWriter struct {
+	w *bufio.Writer
+}
+
+func (w *ndjsonExportWriter) Write(m map[string]interface{}) error {
+	b, err := api.MarshalRaw(m)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.w.Write(b); err != nil {
+		return err
+	}
+
+	return w.w.WriteByte('\n')
+}
+
+func (w *ndjsonExportWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type csvExportWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func newCSVExportWriter(w io.Writer, columns []string) (*csvExportWriter, error) {
+	cw := &csvExportWriter{w: csv.NewWriter(w), columns: columns}
+
+	return cw, cw.w.Write(columns)
+}
+
+// csvExportColumns returns response fields included in projection in order of serializer's field names.
+func csvExportColumns(serializer serializers.DataObjectSerializer) []string {
+	var columns []string
+
+	for _, name := range serializer.FieldNames() {
+		if serializer.Projection.Includes(name) {
+			columns = append(columns, name)
+		}
+	}
+
+	return columns
+}
+
+func (w *csvExportWriter) Write(m map[string]interface{}) error {
+	record := make([]string, len(w.columns))
+
+	for i, name := range w.columns {
+		val, err := w.value(m[name])
+		if err != nil {
+			return err
+		}
+
+		record[i] = val
+	}
+
+	return w.w.Write(record)
+}
+
+// value returns CSV cell of value. Strings are written as is, other values as JSON.
+func (w *csvExportWriter) value(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+
+	b, err := api.MarshalRaw(v)
+	if err != nil {
+		return "", err
+	}
+
+	var s string
+
+	switch {
+	case string(b) == "null":
+		return "", nil
+	case json.Unmarshal(b, &s) == nil:
+		return s, nil
+	}
+
+	return string(b), nil
+}
+
+func (w *csvExportWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// DataObjectExport streams all data objects matching query as NDJSON or CSV. Objects are fetched in batches
+// from server-side cursor so that memory usage does not depend on class size.
+func (ctr *Controller) DataObjectExport(c echo.Context) error {
+	var o []*models.DataObject
+
+	format := util.NonEmptyString(c.QueryParam(exportFormatQuery), exportFormatNDJSON)
+
+	if _, ok := exportContentTypes[format]; !ok {
+		return newFormatError()
+	}
+
+	mgr := ctr.q.NewDataObjectManager(c)
+	class := c.Get(contextClassKey).(*models.Class)
+	q := mgr.ForClassQ(class, &o)
+
+	if _, e := c.QueryParams()["query"]; e {
+		var err error
+
+		if q, err = NewDataObjectQuery(class.FilterFields()).Parse(ctr.q, c, q); err != nil {
+			return err
+		}
+	}
+
+	serializer := serializers.DataObjectSerializer{Class: class}
+
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
+		return err
+	}
+
+	serializer.Projection = projection
+
+	sql, err := q.OrderExpr("?TableAlias.id ASC").AppendQuery(query.TenantDB(c, ctr.db).Formatter(), nil)
+	if err != nil {
+		return err
+	}
+
+	return mgr.RunInTransaction(func(tx *pg.Tx) error {
+		if _, err := tx.Exec(fmt.Sprintf("DECLARE %s NO SCROLL CURSOR FOR ?", exportCursorName), pg.Safe(string(sql))); err != nil {
+			return err
+		}
+
+		return ctr.writeDataObjectExport(c, class.Name, format, serializer, func() ([]*models.DataObject, error) {
+			var objs []*models.DataObject
+
+			_, err := tx.Query(&objs, fmt.Sprintf("FETCH %d FROM %s", exportFetchSize, exportCursorName))
+
+			return objs, err
+		})
+	})
+}
+
+// writeDataObjectExport writes export of objects fetched in batches until there are none left. First batch is fetched
+// before response is committed so that its errors are rendered as usual. Errors after that abort connection instead,
+// so that client does not take truncated export for a complete one.
+func (ctr *Controller) writeDataObjectExport(c echo.Context, name, format string, serializer serializers.DataObjectSerializer,
+	fetch func() ([]*models.DataObject, error)) error {
+	objs, err := fetch()
+	if err != nil {
+		return err
+	}
+
+	resp := c.Response()
+	resp.Header().Set(echo.HeaderContentType, exportContentTypes[format])
+	resp.Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	resp.WriteHeader(http.StatusOK)
+
+	if err := writeExportBatches(resp, format, serializer, objs, fetch); err != nil {
+		ctr.log.Logger().With(zap.Error(err)).Error("Data object export failed")
+		panic(http.ErrAbortHandler)
+	}
+
+	return nil
+}
+
+// writeExportBatches writes objects of first batch and ones fetched after it in export format.
+func writeExportBatches(resp *echo.Response, format string, serializer serializers.DataObjectSerializer,
+	objs []*models.DataObject, fetch func() ([]*models.DataObject, error)) error {
+	var (
+		w   exportWriter
+		err error
+	)
+
+	if format == exportFormatCSV {
+		if w, err = newCSVExportWriter(resp, csvExportColumns(serializer)); err != nil {
+			return err
+		}
+	} else {
+		w = &ndjsonExportWriter{w: bufio.NewWriter(resp)}
+	}
+
+	for len(objs) > 0 {
+		for _, obj := range objs {
+			if err := w.Write(serializer.Response(obj).(map[string]interface{})); err != nil {
+				return err
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			return err
+		}
+
+		resp.Flush()
+
+		if objs, err = fetch(); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
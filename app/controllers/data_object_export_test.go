@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/serializers"
+)
+
+func TestExportWriters(t *testing.T) {
+	Convey("Given serialized objects", t, func() {
+		var buf bytes.Buffer
+
+		m := map[string]interface{}{"id": 1, "name": "a,b", "tags": []string{"x"}, "empty": nil}
+
+		Convey("NDJSON writes object per line", func() {
+			w := &ndjsonExportWriter{w: bufio.NewWriter(&buf)}
+			So(w.Write(map[string]interface{}{"id": 1}), ShouldBeNil)
+			So(w.Write(map[string]interface{}{"id": 2}), ShouldBeNil)
+			So(w.Flush(), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "{\"id\":1}\n{\"id\":2}\n")
+		})
+		Convey("CSV writes header and values in column order", func() {
+			w, err := newCSVExportWriter(&buf, []string{"name", "id", "tags", "empty", "missing"})
+			So(err, ShouldBeNil)
+			So(w.Write(m), ShouldBeNil)
+			So(w.Flush(), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "name,id,tags,empty,missing\n\"a,b\",1,\"[\"\"x\"\"]\",,\n")
+		})
+	})
+}
+
+func TestCSVExportColumns(t *testing.T) {
+	Convey("Given class with schema", t, func() {
+		class := models.NewClass()
+		class.Name = "cls"
+		So(class.SetSchema([]map[string]interface{}{
+			{"name": "c", "type": models.FieldStringType},
+			{"name": "a", "type": models.FieldIntegerType},
+			{"name": "b", "type": models.FieldTextType},
+		}, map[string]string{"a": "_a", "b": "_b", "c": "_c"}), ShouldBeNil)
+
+		Convey("columns are stable and follow declared schema order", func() {
+			columns := csvExportColumns(serializers.DataObjectSerializer{Class: class})
+			So(columns[len(columns)-3:], ShouldResemble, []string{"c", "a", "b"})
+
+			for i := 0; i < 10; i++ {
+				So(csvExportColumns(serializers.DataObjectSerializer{Class: class}), ShouldResemble, columns)
+			}
+		})
+		Convey("columns are limited by projection", func() {
+			s := serializers.DataObjectSerializer{Class: class, Projection: serializers.NewProjection([]string{"b", "id", "c"}, nil)}
+			So(csvExportColumns(s), ShouldResemble, []string{"id", "c", "b"})
+		})
+	})
+}
+
+func TestDataObjectExportFormat(t *testing.T) {
+	Convey("Unknown export format is rejected", t, func() {
+		req := httptest.NewRequest(http.MethodGet, "/?format=xml", nil)
+		err := (&Controller{}).DataObjectExport(echo.New().NewContext(req, httptest.NewRecorder()))
+		So(err, ShouldNotBeNil)
+		So(err.(*api.Error).Code, ShouldEqual, http.StatusBadRequest)
+	})
+}
+
+func TestWriteDataObjectExport(t *testing.T) {
+	Convey("Given objects fetched in batches", t, func() {
+		class := models.NewClass()
+		class.Name = "cls"
+		So(class.SetSchema([]map[string]interface{}{
+			{"name": "a", "type": models.FieldStringType},
+		}, map[string]string{"a": "_a"}), ShouldBeNil)
+
+		serializer := serializers.DataObjectSerializer{Class: class, Projection: serializers.NewProjection([]string{"id"}, nil)}
+		newObject := func(id int) *models.DataObject {
+			o := models.NewDataObject(class)
+			o.ID = id
+
+			return o
+		}
+		errFetch := errors.New("fetch failed")
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+		fetcher := func(batches ...interface{}) func() ([]*models.DataObject, error) {
+			return func() ([]*models.DataObject, error) {
+				if len(batches) == 0 {
+					return nil, nil
+				}
+
+				b := batches[0]
+				batches = batches[1:]
+
+				if err, ok := b.(error); ok {
+					return nil, err
+				}
+
+				return b.([]*models.DataObject), nil
+			}
+		}
+
+		Convey("all batches are written", func() {
+			err := (&Controller{}).writeDataObjectExport(c, class.Name, exportFormatNDJSON, serializer,
+				fetcher([]*models.DataObject{newObject(1), newObject(2)}, []*models.DataObject{newObject(3)}))
+			So(err, ShouldBeNil)
+			So(rec.Code, ShouldEqual, http.StatusOK)
+			So(rec.Header().Get(echo.HeaderContentDisposition), ShouldEqual, `attachment; filename="cls.ndjson"`)
+			So(rec.Body.String(), ShouldEqual, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")
+		})
+		Convey("CSV of no objects has header only", func() {
+			So((&Controller{}).writeDataObjectExport(c, class.Name, exportFormatCSV, serializer, fetcher()), ShouldBeNil)
+			So(rec.Header().Get(echo.HeaderContentType), ShouldEqual, exportContentTypes[exportFormatCSV])
+			So(rec.Body.String(), ShouldEqual, "id\n")
+		})
+		Convey("error of first batch is returned before response is committed", func() {
+			err := (&Controller{}).writeDataObjectExport(c, class.Name, exportFormatNDJSON, serializer, fetcher(errFetch))
+			So(err, ShouldEqual, errFetch)
+			So(c.Response().Committed, ShouldBeFalse)
+		})
+		Convey("error of later batch aborts committed response", func() {
+			So(func() {
+				_ = (&Controller{}).writeDataObjectExport(c, class.Name, exportFormatNDJSON, serializer,
+					fetcher([]*models.DataObject{newObject(1)}, errFetch))
+			}, ShouldPanic)
+			So(c.Response().Committed, ShouldBeTrue)
+			So(rec.Body.String(), ShouldEqual, "{\"id\":1}\n")
+		})
+	})
+}
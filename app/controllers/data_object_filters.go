@@ -27,6 +27,12 @@ const (
 	maxListLength = 16
 )
 
+// Index type hints of lookups.
+const (
+	indexTypeGIN  = "gin"
+	indexTypeGIST = "gist"
+)
+
 func registerFilter(op *filterOp, lookups ...string) {
 	for _, lookup := range lookups {
 		filters[lookup] = append(filters[lookup], op)
@@ -42,11 +48,16 @@ type filterOp struct {
 	expectedListValue []reflect.Kind
 	query             func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query
 	validate          func(c echo.Context, qf *query.Factory, q *filterOp, f models.FilterField, val interface{}) (interface{}, error)
+	track             func(doq *DataObjectQuery, f models.FilterField, data interface{})
+	// index is a hint of index type that lookup requires to be efficient, empty for default btree.
+	index string
 }
 
 func (op *filterOp) Supports(f models.FilterField) bool {
-	typ := f.Type()
+	return op.supportsType(f.Type())
+}
 
+func (op *filterOp) supportsType(typ string) bool {
 	if op.supportedTypes != nil {
 		for _, t := range op.supportedTypes {
 			if t == typ {
@@ -68,6 +79,24 @@ func (op *filterOp) Supports(f models.FilterField) bool {
 	return true
 }
 
+// lookupIndexTypes returns index types hinted by lookups supported for field type.
+func lookupIndexTypes(typ string) []string {
+	var ret []string
+
+	seen := make(map[string]struct{})
+
+	for _, ops := range filters {
+		for _, op := range ops {
+			if _, ok := seen[op.index]; !ok && op.index != "" && op.supportsType(typ) {
+				seen[op.index] = struct{}{}
+				ret = append(ret, op.index)
+			}
+		}
+	}
+
+	return ret
+}
+
 func (op *filterOp) Process(c echo.Context, qf *query.Factory, doq *DataObjectQuery, q *orm.Query, f models.FilterField, lookup string, data interface{}) (*orm.Query, error) {
 	var ok bool
 
@@ -96,13 +125,19 @@ func (op *filterOp) Process(c echo.Context, qf *query.Factory, doq *DataObjectQu
 		return nil, newQueryError(fmt.Sprintf(`Validation of value provided "%s" lookup of field "%s" failed.`, lookup, f.Name()))
 	}
 
+	if op.track != nil && doq.tracksLookups() {
+		op.track(doq, f, data)
+	}
+
 	return op.query(c, qf, q, f, lookup, data), nil
 }
 
 func (op *filterOp) validateKind(k reflect.Kind, expected []reflect.Kind, val interface{}) (interface{}, bool) {
 	for _, v := range expected {
+		// Numbers in JSON are decoded as float64, only whole ones are valid integers.
 		if k == reflect.Float64 && v == reflect.Int {
-			return int(val.(float64)), true
+			i, ok := toInteger(val)
+			return i, ok
 		}
 
 		if k == v {
@@ -213,7 +248,7 @@ func init() {
 	}
 
 	registerFilter(&filterOp{
-		unsupportedTypes: []string{models.FieldRelationType, models.FieldArrayType, models.FieldGeopointType},
+		unsupportedTypes: []string{models.FieldRelationType, models.FieldArrayType, models.FieldGeopointType, models.FieldObjectType, fieldJSONPathType},
 		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
 			return q.Where(fmt.Sprintf("%s %s ?", f.SQLName(), simpleLookups[op]), data)
 		}},
@@ -243,10 +278,83 @@ func init() {
 		"_contains", "_icontains", "_startswith", "_istartswith", "_endswith", "_iendswith", "_like", "_ilike", "_ieq",
 	)
 
+	// Full text search.
+	registerFilter(&filterOp{
+		expectedValue:  []reflect.Kind{reflect.String},
+		supportedTypes: []string{models.FieldStringType, models.FieldTextType},
+		index:          indexTypeGIN,
+		validate: func(c echo.Context, qf *query.Factory, op *filterOp, f models.FilterField, val interface{}) (interface{}, error) {
+			s := strings.TrimSpace(val.(string))
+			if s == "" || len(s) > searchMaxLength {
+				return nil, nil
+			}
+
+			return s, nil
+		},
+
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			return q.Where(fmt.Sprintf("%s @@ websearch_to_tsquery('%s', ?)", searchVectorSQL(f), searchLanguage(f)), data)
+		},
+
+		track: func(doq *DataObjectQuery, f models.FilterField, data interface{}) {
+			doq.ranks = append(doq.ranks, searchRankSQL(f, data.(string)))
+		}},
+		"_search",
+	)
+
+	// Datetime ranges - date, year, between.
+	var datetimeRangeLookups = map[string]func(interface{}) interface{}{
+		"_date":    validateDateLookup,
+		"_year":    validateYearLookup,
+		"_between": validateBetweenLookup,
+	}
+
+	for lookup, validateRange := range datetimeRangeLookups {
+		validateRange := validateRange
+
+		registerFilter(&filterOp{
+			expectedValue:  []reflect.Kind{reflect.String, reflect.Float64, reflect.Slice, reflect.Map},
+			supportedTypes: []string{models.FieldDatetimeType},
+			validate: func(c echo.Context, qf *query.Factory, op *filterOp, f models.FilterField, val interface{}) (interface{}, error) {
+				return validateRange(val), nil
+			},
+
+			query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+				r := data.(*datetimeRange)
+				sqlOp := "<"
+				if r.Inclusive {
+					sqlOp = "<="
+				}
+				return q.Where(fmt.Sprintf("%s >= ? AND %s %s ?", f.SQLName(), f.SQLName(), sqlOp), r.From, r.To)
+			}},
+			lookup,
+		)
+	}
+
+	// Datetime parts - month, day, week_day. Compared in local time of timezone.
+	for lookup := range datetimeParts {
+		lookup := lookup
+
+		registerFilter(&filterOp{
+			expectedValue:  []reflect.Kind{reflect.Float64, reflect.Map},
+			supportedTypes: []string{models.FieldDatetimeType},
+			validate: func(c echo.Context, qf *query.Factory, op *filterOp, f models.FilterField, val interface{}) (interface{}, error) {
+				return validatePartLookup(lookup, val), nil
+			},
+
+			query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+				p := data.(*datetimePart)
+				return q.Where(fmt.Sprintf("EXTRACT(%s FROM %s AT TIME ZONE ?) = ?", datetimeParts[op].sqlField, f.SQLName()),
+					p.Timezone, p.Value)
+			}},
+			lookup,
+		)
+	}
+
 	// Container filters - in, nin.
 	registerFilter(&filterOp{
 		expectList:       true,
-		unsupportedTypes: []string{models.FieldRelationType, models.FieldArrayType, models.FieldGeopointType},
+		unsupportedTypes: []string{models.FieldRelationType, models.FieldArrayType, models.FieldGeopointType, models.FieldObjectType, fieldJSONPathType},
 		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
 			sqlOp := "IN"
 			if op == "_nin" {
@@ -263,6 +371,7 @@ func init() {
 		expectList:        true,
 		expectedListValue: []reflect.Kind{reflect.Int},
 		supportedTypes:    []string{models.FieldRelationType},
+		index:             indexTypeGIN,
 		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
 			return q.Where(fmt.Sprintf("%s @> ?", f.SQLName()), pg.Array(data))
 		}},
@@ -274,6 +383,7 @@ func init() {
 		expectList:        true,
 		expectedListValue: []reflect.Kind{reflect.String, reflect.Bool, reflect.Float64, reflect.Int},
 		supportedTypes:    []string{models.FieldArrayType},
+		index:             indexTypeGIN,
 		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
 			arr, err := json.Marshal(data)
 			if err != nil {
@@ -284,6 +394,111 @@ func init() {
 		"_contains",
 	)
 
+	// Array contained by.
+	registerFilter(&filterOp{
+		expectList:        true,
+		expectedListValue: []reflect.Kind{reflect.String, reflect.Bool, reflect.Float64, reflect.Int},
+		supportedTypes:    []string{models.FieldArrayType},
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			return q.Where(fmt.Sprintf("%s <@ ?", f.SQLName()), jsonValue(data))
+		}},
+		"_contained_by",
+	)
+
+	// Array overlaps - contains any of values.
+	registerFilter(&filterOp{
+		expectList:        true,
+		expectedListValue: []reflect.Kind{reflect.String, reflect.Bool, reflect.Float64, reflect.Int},
+		supportedTypes:    []string{models.FieldArrayType},
+		index:             indexTypeGIN,
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			return q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+				for _, v := range data.([]interface{}) {
+					q = q.WhereOr(fmt.Sprintf("%s @> ?", f.SQLName()), jsonValue([]interface{}{v}))
+				}
+				return q, nil
+			})
+		}},
+		"_overlaps",
+	)
+
+	// Array length.
+	registerFilter(&filterOp{
+		expectedValue:  []reflect.Kind{reflect.Int},
+		supportedTypes: []string{models.FieldArrayType},
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			sqlOp := ">"
+			if op == "_length_lt" {
+				sqlOp = "<"
+			}
+			return q.Where(fmt.Sprintf("jsonb_array_length(%s) %s ?", f.SQLName(), sqlOp), data)
+		}},
+		"_length_gt", "_length_lt",
+	)
+
+	// Object path - nested query on values of object field.
+	registerFilter(&filterOp{
+		expectedValue:  []reflect.Kind{reflect.Map},
+		supportedTypes: []string{models.FieldObjectType},
+		index:          indexTypeGIN,
+		validate: func(c echo.Context, qf *query.Factory, op *filterOp, f models.FilterField, val interface{}) (interface{}, error) {
+			m := val.(map[string]interface{})
+			if len(m) == 0 {
+				return nil, nil
+			}
+
+			return newJSONPathQuery(c, qf, f, m)
+		},
+
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			return data.(*jsonPathQuery).apply(c, qf, q)
+		}},
+		"_path",
+	)
+
+	// JSON path comparisons. Values are compared as jsonb so mismatched types never fail.
+	registerFilter(&filterOp{
+		expectedValue:  []reflect.Kind{reflect.String, reflect.Float64, reflect.Bool},
+		supportedTypes: []string{fieldJSONPathType},
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			return q.Where(fmt.Sprintf("%s %s ?", f.SQLName(), simpleLookups[op]), jsonValue(data))
+		}},
+		"_gt", "_gte", "_lt", "_lte", "_eq", "_neq",
+	)
+
+	// JSON path containers - in, nin.
+	registerFilter(&filterOp{
+		expectList:        true,
+		expectedListValue: []reflect.Kind{reflect.String, reflect.Float64, reflect.Bool},
+		supportedTypes:    []string{fieldJSONPathType},
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			sqlOp := "IN"
+			if op == "_nin" {
+				sqlOp = "NOT IN"
+			}
+
+			lst := data.([]interface{})
+			vals := make([]string, len(lst))
+
+			for i, v := range lst {
+				vals[i] = jsonValue(v)
+			}
+
+			return q.Where(fmt.Sprintf("%s %s (?)", f.SQLName(), sqlOp), pg.In(vals))
+		}},
+		"_in", "_nin",
+	)
+
+	// JSON path contains.
+	registerFilter(&filterOp{
+		expectedValue:  []reflect.Kind{reflect.Map, reflect.Slice, reflect.String, reflect.Float64, reflect.Bool},
+		supportedTypes: []string{fieldJSONPathType},
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			return q.Where(fmt.Sprintf("%s @> ?", f.SQLName()), jsonValue(data))
+		}},
+		"_contains",
+	)
+
 	// Geo near.
 	type nearLookup struct {
 		Longitude            float64 `validate:"gt=-180,lt=180,required"`
@@ -295,6 +510,7 @@ func init() {
 	registerFilter(&filterOp{
 		expectedValue:  []reflect.Kind{reflect.Map},
 		supportedTypes: []string{models.FieldGeopointType},
+		index:          indexTypeGIST,
 		validate: func(c echo.Context, qf *query.Factory, op *filterOp, f models.FilterField, val interface{}) (interface{}, error) {
 			l := &nearLookup{}
 			if mapstructure.Decode(val, l) != nil || validate.Struct(l) != nil {
@@ -318,6 +534,73 @@ func init() {
 		"_near",
 	)
 
+	// Geo within radius. Tracks center so that distance can be returned and used for ordering.
+	registerFilter(&filterOp{
+		expectedValue:  []reflect.Kind{reflect.Map},
+		supportedTypes: []string{models.FieldGeopointType},
+		index:          indexTypeGIST,
+		validate: func(c echo.Context, qf *query.Factory, op *filterOp, f models.FilterField, val interface{}) (interface{}, error) {
+			l := &radiusLookup{}
+			if mapstructure.Decode(val, l) != nil || validate.Struct(l) != nil || l.Meters() <= 0 {
+				return nil, nil
+			}
+
+			return l, nil
+		},
+
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			l := data.(*radiusLookup)
+			return q.Where(fmt.Sprintf("ST_DWithin(%s, ST_GeomFromEWKB(?)::geography, ?, false)", f.SQLName()),
+				&ewkb.Point{Point: l.Point()}, l.Meters())
+		},
+
+		track: func(doq *DataObjectQuery, f models.FilterField, data interface{}) {
+			doq.distances[f.Name()] = data.(*radiusLookup).Point()
+		}},
+		"_within_radius",
+	)
+
+	// Geo within bounding box.
+	registerFilter(&filterOp{
+		expectedValue:  []reflect.Kind{reflect.Map},
+		supportedTypes: []string{models.FieldGeopointType},
+		index:          indexTypeGIST,
+		validate: func(c echo.Context, qf *query.Factory, op *filterOp, f models.FilterField, val interface{}) (interface{}, error) {
+			l := &bboxLookup{}
+			if mapstructure.Decode(val, l) != nil || validate.Struct(l) != nil || !l.Valid() {
+				return nil, nil
+			}
+
+			return l.Polygon(), nil
+		},
+
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			return q.Where(fmt.Sprintf("%s::geometry && ST_GeomFromEWKB(?)", f.SQLName()),
+				&ewkb.Polygon{Polygon: data.(*geom.Polygon)})
+		}},
+		"_within_bbox",
+	)
+
+	// Geo within GeoJSON polygon.
+	registerFilter(&filterOp{
+		expectedValue:  []reflect.Kind{reflect.Map},
+		supportedTypes: []string{models.FieldGeopointType},
+		index:          indexTypeGIST,
+		validate: func(c echo.Context, qf *query.Factory, op *filterOp, f models.FilterField, val interface{}) (interface{}, error) {
+			if poly := geoJSONPolygon(val); poly != nil {
+				return poly, nil
+			}
+
+			return nil, nil
+		},
+
+		query: func(c echo.Context, qf *query.Factory, q *orm.Query, f models.FilterField, op string, data interface{}) *orm.Query {
+			return q.Where(fmt.Sprintf("ST_Covers(ST_GeomFromEWKB(?)::geography, %s)", f.SQLName()),
+				&ewkb.Polygon{Polygon: data.(*geom.Polygon)})
+		}},
+		"_within_polygon",
+	)
+
 	// Reference and relation is.
 	registerFilter(&filterOp{
 		expectedValue:  []reflect.Kind{reflect.Map},
@@ -344,6 +627,10 @@ func init() {
 				return nil, newQueryError("Referenced class " + cls.Name + " does not exist.")
 			}
 
+			if err := checkClassPermission(c, qf, cls, models.ClassPermissionList); err != nil {
+				return nil, err
+			}
+
 			// Process subquery.
 			var q *orm.Query
 			switch cls.Name {
@@ -0,0 +1,515 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-pg/pg/v9/orm"
+	"github.com/jackc/pgtype"
+	"github.com/jinzhu/now"
+	json "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+	geom "github.com/twpayne/go-geom"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+	"github.com/Syncano/pkg-go/v2/database/fields"
+)
+
+const (
+	dataObjectStringMaxLength   = 128
+	dataObjectTextMaxLength     = 32000
+	dataObjectRelationMaxLength = 1000
+	geopointSRID                = 4326
+	expectedRevisionKey         = "expected_revision"
+)
+
+// fieldError is a validation error of a single data object field.
+type fieldError string
+
+func (e fieldError) Error() string {
+	return string(e)
+}
+
+func newFieldError(format string, a ...interface{}) fieldError {
+	return fieldError(fmt.Sprintf(format, a...))
+}
+
+var (
+	errFieldInvalidObject   = newFieldError("Not a valid JSON object.")
+	errFieldInvalidArray    = newFieldError("Not a valid JSON array.")
+	errFieldInvalidList     = newFieldError("Expected a list of items.")
+	errFieldInvalidGeopoint = newFieldError("Not a valid geopoint.")
+)
+
+// dataObjectPayload holds data object values and files parsed from request body.
+type dataObjectPayload struct {
+	data  map[string]interface{}
+	files map[string]*multipart.FileHeader
+}
+
+func parseDataObjectPayload(c echo.Context) (*dataObjectPayload, error) {
+	p := &dataObjectPayload{
+		data:  make(map[string]interface{}),
+		files: make(map[string]*multipart.FileHeader),
+	}
+	req := c.Request()
+
+	if req.ContentLength == 0 {
+		return p, nil
+	}
+
+	ctype := req.Header.Get(echo.HeaderContentType)
+
+	switch {
+	case strings.HasPrefix(ctype, echo.MIMEMultipartForm):
+		f, err := c.MultipartForm()
+		if err != nil {
+			return nil, api.NewBadRequestError("Parsing payload failure: invalid form data.")
+		}
+
+		for k, vals := range f.Value {
+			p.data[k] = vals[0]
+		}
+
+		for k, vals := range f.File {
+			p.files[k] = vals[0]
+		}
+
+	case strings.HasPrefix(ctype, echo.MIMEApplicationForm):
+		values, err := c.FormParams()
+		if err != nil {
+			return nil, api.NewBadRequestError("Parsing payload failure: invalid form data.")
+		}
+
+		for k, vals := range values {
+			p.data[k] = vals[0]
+		}
+
+	default:
+		data, err := api.ParsedData(c)
+
+		switch {
+		case err == echo.ErrUnsupportedMediaType:
+			return nil, err
+		case err == io.EOF:
+			return p, nil
+		case err != nil:
+			return nil, api.NewBadRequestError("Parsing payload failure: invalid JSON.")
+		}
+
+		for k, v := range data {
+			p.data[k] = v
+		}
+	}
+
+	return p, nil
+}
+
+// dataObjectFieldValue validates and converts value from request payload to field's internal type.
+// nolint: gocyclo
+func dataObjectFieldValue(f *models.DataObjectField, val interface{}) (interface{}, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	s, isString := val.(string)
+
+	switch f.FType {
+	case models.FieldStringType, models.FieldTextType:
+		if !isString {
+			return nil, newFieldError("Not a valid string.")
+		}
+
+		maxLength := dataObjectStringMaxLength
+		if f.FType == models.FieldTextType {
+			maxLength = dataObjectTextMaxLength
+		}
+
+		if utf8.RuneCountInString(s) > maxLength {
+			return nil, newFieldError("Ensure this field has no more than %d characters.", maxLength)
+		}
+
+		return s, nil
+
+	case models.FieldIntegerType, models.FieldReferenceType:
+		if m, ok := val.(map[string]interface{}); ok && f.FType == models.FieldReferenceType {
+			val = m["value"]
+		}
+
+		if v, ok := toInteger(val); ok {
+			return v, nil
+		}
+
+		return nil, newFieldError("A valid integer is required.")
+
+	case models.FieldFloatType:
+		fv, ok := toFloat(val)
+		if isString {
+			var err error
+			fv, err = strconv.ParseFloat(s, 64)
+			ok = err == nil
+		}
+
+		if ok && !math.IsInf(fv, 0) && !math.IsNaN(fv) {
+			return fv, nil
+		}
+
+		return nil, newFieldError("A valid number is required.")
+
+	case models.FieldBooleanType:
+		switch v := val.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if bv, err := strconv.ParseBool(v); err == nil {
+				return bv, nil
+			}
+		}
+
+		return nil, newFieldError("Must be a valid boolean.")
+
+	case models.FieldDatetimeType:
+		if m, ok := val.(map[string]interface{}); ok {
+			s, isString = m["value"].(string)
+		}
+
+		if isString {
+			if t, err := now.ParseInLocation(time.UTC, s); err == nil {
+				return fields.NewTime(&t), nil
+			}
+		}
+
+		return nil, newFieldError("Datetime has wrong format.")
+
+	case models.FieldRelationType:
+		return toRelationValue(val)
+
+	case models.FieldObjectType:
+		if isString && json.Unmarshal([]byte(s), &val) != nil {
+			return nil, errFieldInvalidObject
+		}
+
+		if _, ok := val.(map[string]interface{}); !ok {
+			return nil, errFieldInvalidObject
+		}
+
+		return val, nil
+
+	case models.FieldArrayType:
+		if isString && json.Unmarshal([]byte(s), &val) != nil {
+			return nil, errFieldInvalidArray
+		}
+
+		if _, ok := val.([]interface{}); !ok {
+			return nil, errFieldInvalidArray
+		}
+
+		return val, nil
+
+	case models.FieldGeopointType:
+		return toGeopointValue(val)
+	}
+
+	return nil, newFieldError("Unsupported field type.")
+}
+
+// toFloat returns number decoded from payload as float64. Binary formats decode numbers to any integer
+// or float type depending on their encoding.
+func toFloat(val interface{}) (float64, bool) {
+	v := reflect.ValueOf(val)
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	}
+
+	return 0, false
+}
+
+// toInteger returns integer of whole number or numeric string from payload within 32 bit range.
+func toInteger(val interface{}) (int, bool) {
+	if s, ok := val.(string); ok {
+		if iv, err := strconv.ParseInt(s, 10, 32); err == nil {
+			return int(iv), true
+		}
+
+		return 0, false
+	}
+
+	if v, ok := toFloat(val); ok && v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+		return int(v), true
+	}
+
+	return 0, false
+}
+
+func toRelationValue(val interface{}) (interface{}, error) {
+	if m, ok := val.(map[string]interface{}); ok {
+		val = m["value"]
+	}
+
+	if s, ok := val.(string); ok && json.Unmarshal([]byte(s), &val) != nil {
+		return nil, errFieldInvalidList
+	}
+
+	lst, ok := val.([]interface{})
+	if !ok {
+		return nil, errFieldInvalidList
+	}
+
+	if len(lst) > dataObjectRelationMaxLength {
+		return nil, newFieldError("Ensure this field has no more than %d elements.", dataObjectRelationMaxLength)
+	}
+
+	ret := make([]int, 0, len(lst))
+	seen := make(map[int]struct{}, len(lst))
+
+	for _, v := range lst {
+		id, ok := toInteger(v)
+		if !ok {
+			return nil, newFieldError("Incorrect type. Expected pk value.")
+		}
+
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			ret = append(ret, id)
+		}
+	}
+
+	return ret, nil
+}
+
+func toGeopointValue(val interface{}) (interface{}, error) {
+	if s, ok := val.(string); ok && json.Unmarshal([]byte(s), &val) != nil {
+		return nil, errFieldInvalidGeopoint
+	}
+
+	m, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, errFieldInvalidGeopoint
+	}
+
+	lng, lngOk := toFloat(m["longitude"])
+	lat, latOk := toFloat(m["latitude"])
+
+	if !lngOk || !latOk || lng < -180 || lng > 180 || lat < -90 || lat > 90 {
+		return nil, errFieldInvalidGeopoint
+	}
+
+	return geom.NewPointFlat(geom.XY, []float64{lng, lat}).SetSRID(geopointSRID), nil
+}
+
+// dataObjectSize returns approximate size of data object hstore.
+func dataObjectSize(o *models.DataObject) int {
+	size := 0
+
+	for k, v := range o.Data.Map {
+		size += len(k) + len(v.String)
+	}
+
+	return size
+}
+
+func initDataObjectHstores(o *models.DataObject) {
+	if o.Data.Map == nil {
+		o.Data.Set(map[string]string{}) // nolint: errcheck
+	}
+
+	if o.Files.Map == nil {
+		o.Files.Set(map[string]string{}) // nolint: errcheck
+	}
+}
+
+// bindDataObject validates payload against class schema and sets converted values on data object.
+// Update operators are applied on current values so object should be locked beforehand.
+// Files are uploaded only after all fields were validated successfully and storage indicator is updated accordingly.
+// nolint: gocyclo
+func (ctr *Controller) bindDataObject(c echo.Context, db orm.DB, class *models.Class, o *models.DataObject, p *dataObjectPayload) error {
+	var (
+		val interface{}
+		err error
+	)
+
+	errs := make(map[string]interface{})
+	values := make(map[*models.DataObjectField]interface{})
+	files := make(map[*models.DataObjectField]*multipart.FileHeader)
+
+	initDataObjectHstores(o)
+
+	// New objects get defaults of missing fields and need to define all required ones.
+	isNew := o.ID == 0
+
+	for name, f := range class.ComputedSchema() {
+		if f.FType == models.FieldFileType {
+			if fh, ok := p.files[name]; ok {
+				files[f] = fh
+			} else if v, ok := p.data[name]; ok {
+				if v != nil {
+					errs[name] = []string{"The submitted data was not a file. Check the encoding type on the form."}
+					continue
+				}
+
+				if f.Required {
+					errs[name] = []string{errFieldNull.Error()}
+					continue
+				}
+
+				values[f] = nil
+			} else if isNew && f.Required {
+				errs[name] = []string{errFieldRequired.Error()}
+			}
+
+			continue
+		}
+
+		v, ok := p.data[name]
+		if !ok {
+			if !isNew {
+				continue
+			}
+
+			if v = f.Default; v == nil {
+				if f.Required {
+					errs[name] = []string{errFieldRequired.Error()}
+				}
+
+				continue
+			}
+		}
+
+		if val, err = dataObjectFieldUpdate(f, o, v); err == nil {
+			err = checkFieldConstraints(f, val)
+		}
+
+		if err != nil {
+			errs[name] = []string{err.Error()}
+			continue
+		}
+
+		values[f] = val
+	}
+
+	if len(errs) > 0 {
+		return api.NewError(http.StatusBadRequest, errs)
+	}
+
+	if err = checkUniqueFields(db, class, o, values); err != nil {
+		return err
+	}
+
+	sizeDiff := 0
+
+	for f, v := range values {
+		if f.FType == models.FieldFileType {
+			sizeDiff -= ctr.removeDataObjectFile(c.Request().Context(), db, o, f)
+		}
+
+		if err = f.Set(o.Data, v); err != nil {
+			return api.NewError(http.StatusBadRequest, map[string]interface{}{f.FName: []string{err.Error()}})
+		}
+	}
+
+	if dataObjectSize(o) > settings.API.DataObjectMaxSize {
+		return api.NewBadRequestError(fmt.Sprintf("Object size exceeds the limit (%d bytes).", settings.API.DataObjectMaxSize))
+	}
+
+	// Upload files, replacing old ones.
+	instance := c.Get(settings.ContextInstanceKey).(*models.Instance)
+
+	for f, fh := range files {
+		sizeDiff -= ctr.removeDataObjectFile(c.Request().Context(), db, o, f)
+
+		key, err := ctr.uploadDataObjectFile(c.Request().Context(), db, instance, class, fh)
+		if err != nil {
+			return err
+		}
+
+		o.Data.Map[f.Mapping] = pgtype.Text{String: key, Status: pgtype.Present}
+		o.Files.Map[f.Mapping] = pgtype.Text{String: strconv.FormatInt(fh.Size, 10), Status: pgtype.Present}
+		sizeDiff += int(fh.Size)
+	}
+
+	if sizeDiff != 0 {
+		return ctr.updateInstanceIndicatorValue(c, db, models.InstanceIndicatorTypeStorageSize, sizeDiff)
+	}
+
+	return nil
+}
+
+// setDataObjectOwner sets owner of newly created object to currently authenticated user.
+func setDataObjectOwner(c echo.Context, o *models.DataObject) {
+	if u := c.Get(settings.ContextUserKey); u != nil {
+		o.OwnerID = u.(*models.User).ID
+	}
+}
+
+// removeDataObjectFile removes file of specified field from data object and schedules its deletion from storage
+// after transaction commit. Returns size of removed file.
+func (ctr *Controller) removeDataObjectFile(ctx context.Context, db orm.DB, o *models.DataObject, f *models.DataObjectField) int {
+	size, ok := o.Files.Map[f.Mapping]
+	if !ok {
+		return 0
+	}
+
+	key := o.Data.Map[f.Mapping].String
+
+	delete(o.Files.Map, f.Mapping)
+	delete(o.Data.Map, f.Mapping)
+
+	ctr.addDBCommitHook(ctx, db, func() error {
+		return ctr.fs.Default().Delete(context.Background(), settings.BucketData, key)
+	})
+
+	if d, e := models.ValueFromString(models.FieldIntegerType, size.String); e == nil {
+		return d.(int)
+	}
+
+	return 0
+}
+
+// dataObjectStateFields returns virtual fields of class used for data object state snapshots.
+func dataObjectStateFields(class *models.Class) map[string]models.StateField {
+	virt := make(map[string]models.StateField)
+
+	for name, field := range class.ComputedSchema() {
+		virt[name] = field
+	}
+
+	return virt
+}
+
+// popExpectedRevision removes expected_revision from payload and returns its value (0 if not defined).
+func popExpectedRevision(p *dataObjectPayload) (int, error) {
+	v, ok := p.data[expectedRevisionKey]
+	if !ok {
+		return 0, nil
+	}
+
+	delete(p.data, expectedRevisionKey)
+
+	if v == nil {
+		return 0, nil
+	}
+
+	rev, ok := toInteger(v)
+	if !ok || rev <= 0 {
+		return 0, api.NewError(http.StatusBadRequest, map[string]interface{}{expectedRevisionKey: []string{"A valid integer is required."}})
+	}
+
+	return rev, nil
+}
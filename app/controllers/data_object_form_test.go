@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+	geom "github.com/twpayne/go-geom"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/pkg-go/v2/database/fields"
+)
+
+func TestDataObjectFieldValue(t *testing.T) {
+	Convey("Given data object field value conversion", t, func() {
+		field := func(typ string) *models.DataObjectField {
+			return &models.DataObjectField{FName: "f", FType: typ, Mapping: "_f"}
+		}
+
+		Convey("nil is always accepted", func() {
+			v, err := dataObjectFieldValue(field(models.FieldIntegerType), nil)
+			So(err, ShouldBeNil)
+			So(v, ShouldBeNil)
+		})
+		Convey("string is validated against max length", func() {
+			v, err := dataObjectFieldValue(field(models.FieldStringType), "abc")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, "abc")
+
+			_, err = dataObjectFieldValue(field(models.FieldStringType), strings.Repeat("a", dataObjectStringMaxLength+1))
+			So(err, ShouldNotBeNil)
+
+			v, err = dataObjectFieldValue(field(models.FieldTextType), strings.Repeat("a", dataObjectStringMaxLength+1))
+			So(err, ShouldBeNil)
+			So(v, ShouldHaveLength, dataObjectStringMaxLength+1)
+
+			_, err = dataObjectFieldValue(field(models.FieldStringType), 10.0)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("integer is coerced from number and string", func() {
+			v, err := dataObjectFieldValue(field(models.FieldIntegerType), 10.0)
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 10)
+
+			v, err = dataObjectFieldValue(field(models.FieldIntegerType), "-5")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, -5)
+
+			_, err = dataObjectFieldValue(field(models.FieldIntegerType), 1.5)
+			So(err, ShouldNotBeNil)
+			_, err = dataObjectFieldValue(field(models.FieldIntegerType), "abc")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("reference accepts serialized reference object", func() {
+			v, err := dataObjectFieldValue(field(models.FieldReferenceType), map[string]interface{}{"type": "reference", "value": 3.0})
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 3)
+		})
+		Convey("float rejects non finite values", func() {
+			v, err := dataObjectFieldValue(field(models.FieldFloatType), "1.5")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 1.5)
+
+			_, err = dataObjectFieldValue(field(models.FieldFloatType), "NaN")
+			So(err, ShouldNotBeNil)
+			_, err = dataObjectFieldValue(field(models.FieldFloatType), "Inf")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("float accepts numbers of any type", func() {
+			v, err := dataObjectFieldValue(field(models.FieldFloatType), int8(3))
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 3.0)
+
+			v, err = dataObjectFieldValue(field(models.FieldFloatType), float32(1.5))
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 1.5)
+
+			_, err = dataObjectFieldValue(field(models.FieldFloatType), true)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("boolean is coerced from string", func() {
+			v, err := dataObjectFieldValue(field(models.FieldBooleanType), "true")
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, true)
+
+			_, err = dataObjectFieldValue(field(models.FieldBooleanType), "maybe")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("datetime is parsed in UTC", func() {
+			v, err := dataObjectFieldValue(field(models.FieldDatetimeType), "2020-01-02T03:04:05Z")
+			So(err, ShouldBeNil)
+			So(v.(fields.Time).Time.Year(), ShouldEqual, 2020)
+
+			v, err = dataObjectFieldValue(field(models.FieldDatetimeType), map[string]interface{}{"value": "2020-01-02"})
+			So(err, ShouldBeNil)
+			So(v.(fields.Time).Time.Day(), ShouldEqual, 2)
+
+			_, err = dataObjectFieldValue(field(models.FieldDatetimeType), "not a date")
+			So(err, ShouldNotBeNil)
+		})
+		Convey("object and array accept JSON strings", func() {
+			v, err := dataObjectFieldValue(field(models.FieldObjectType), `{"a": 1}`)
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, map[string]interface{}{"a": 1.0})
+
+			_, err = dataObjectFieldValue(field(models.FieldObjectType), `[1]`)
+			So(err, ShouldEqual, errFieldInvalidObject)
+
+			v, err = dataObjectFieldValue(field(models.FieldArrayType), []interface{}{1.0, "a"})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []interface{}{1.0, "a"})
+
+			_, err = dataObjectFieldValue(field(models.FieldArrayType), `{}`)
+			So(err, ShouldEqual, errFieldInvalidArray)
+		})
+		Convey("unsupported type is rejected", func() {
+			_, err := dataObjectFieldValue(field("unknown"), "a")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestToInteger(t *testing.T) {
+	Convey("Given integer coercion", t, func() {
+		Convey("integral float64 within int32 range is accepted", func() {
+			v, ok := toInteger(42.0)
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 42)
+		})
+		Convey("out of range values are rejected", func() {
+			_, ok := toInteger(1e12)
+			So(ok, ShouldBeFalse)
+			_, ok = toInteger("99999999999")
+			So(ok, ShouldBeFalse)
+		})
+		Convey("integers of any type decoded from binary payloads are accepted", func() {
+			for _, val := range []interface{}{int8(42), int64(42), uint8(42), uint64(42), float32(42)} {
+				v, ok := toInteger(val)
+				So(ok, ShouldBeTrue)
+				So(v, ShouldEqual, 42)
+			}
+
+			_, ok := toInteger(uint64(1) << 40)
+			So(ok, ShouldBeFalse)
+			_, ok = toInteger(float32(1.5))
+			So(ok, ShouldBeFalse)
+		})
+		Convey("unsupported types are rejected", func() {
+			_, ok := toInteger(true)
+			So(ok, ShouldBeFalse)
+			_, ok = toInteger(nil)
+			So(ok, ShouldBeFalse)
+		})
+	})
+}
+
+func TestToFloat(t *testing.T) {
+	Convey("Given float coercion", t, func() {
+		Convey("numbers of any type are accepted", func() {
+			for _, val := range []interface{}{int(-2), int16(-2), int64(-2), float32(-2), float64(-2)} {
+				v, ok := toFloat(val)
+				So(ok, ShouldBeTrue)
+				So(v, ShouldEqual, -2.0)
+			}
+
+			v, ok := toFloat(uint32(7))
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, 7.0)
+		})
+		Convey("non numeric values are rejected", func() {
+			for _, val := range []interface{}{nil, "1", true, []byte{1}} {
+				_, ok := toFloat(val)
+				So(ok, ShouldBeFalse)
+			}
+		})
+	})
+}
+
+func TestToRelationValue(t *testing.T) {
+	Convey("Given relation value coercion", t, func() {
+		Convey("duplicates are removed preserving order", func() {
+			v, err := toRelationValue([]interface{}{3.0, 1.0, 3.0, "2"})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []int{3, 1, 2})
+		})
+		Convey("JSON string and serialized relation object are accepted", func() {
+			v, err := toRelationValue("[1, 2]")
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []int{1, 2})
+
+			v, err = toRelationValue(map[string]interface{}{"type": "relation", "value": []interface{}{5.0}})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []int{5})
+		})
+		Convey("invalid values are rejected", func() {
+			_, err := toRelationValue(5.0)
+			So(err, ShouldEqual, errFieldInvalidList)
+			_, err = toRelationValue([]interface{}{"a"})
+			So(err, ShouldNotBeNil)
+			_, err = toRelationValue(make([]interface{}, dataObjectRelationMaxLength+1))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestToGeopointValue(t *testing.T) {
+	Convey("Given geopoint value coercion", t, func() {
+		Convey("valid point is converted with SRID", func() {
+			v, err := toGeopointValue(`{"longitude": 20.5, "latitude": 50.1}`)
+			So(err, ShouldBeNil)
+
+			p := v.(*geom.Point)
+			So(p.X(), ShouldEqual, 20.5)
+			So(p.Y(), ShouldEqual, 50.1)
+			So(p.SRID(), ShouldEqual, geopointSRID)
+		})
+		Convey("out of range coordinates are rejected", func() {
+			_, err := toGeopointValue(map[string]interface{}{"longitude": 200.0, "latitude": 0.0})
+			So(err, ShouldEqual, errFieldInvalidGeopoint)
+			_, err = toGeopointValue(map[string]interface{}{"longitude": 0.0})
+			So(err, ShouldEqual, errFieldInvalidGeopoint)
+		})
+		Convey("integer coordinates are accepted", func() {
+			v, err := toGeopointValue(map[string]interface{}{"longitude": int8(20), "latitude": uint64(50)})
+			So(err, ShouldBeNil)
+			So(v.(*geom.Point).Coords(), ShouldResemble, geom.Coord{20, 50})
+		})
+	})
+}
+
+func TestParseDataObjectPayload(t *testing.T) {
+	Convey("Given data object request payload", t, func() {
+		e := echo.New()
+		newContext := func(ctype, body string) echo.Context {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, ctype)
+
+			return e.NewContext(req, httptest.NewRecorder())
+		}
+
+		Convey("JSON body is parsed", func() {
+			p, err := parseDataObjectPayload(newContext(echo.MIMEApplicationJSON, `{"a": 1, "b": "x"}`))
+			So(err, ShouldBeNil)
+			So(p.data, ShouldResemble, map[string]interface{}{"a": 1.0, "b": "x"})
+		})
+		Convey("form body is parsed", func() {
+			p, err := parseDataObjectPayload(newContext(echo.MIMEApplicationForm, "a=1&b=x"))
+			So(err, ShouldBeNil)
+			So(p.data, ShouldResemble, map[string]interface{}{"a": "1", "b": "x"})
+		})
+		Convey("invalid JSON results in error", func() {
+			_, err := parseDataObjectPayload(newContext(echo.MIMEApplicationJSON, `{"a"`))
+			So(err, ShouldNotBeNil)
+		})
+		Convey("unsupported media type results in error", func() {
+			_, err := parseDataObjectPayload(newContext(echo.MIMETextPlain, "a"))
+			So(err, ShouldEqual, echo.ErrUnsupportedMediaType)
+		})
+	})
+}
+
+func TestPopExpectedRevision(t *testing.T) {
+	Convey("Given payload with expected revision", t, func() {
+		Convey("revision is removed from payload and returned", func() {
+			p := &dataObjectPayload{data: map[string]interface{}{expectedRevisionKey: 3.0, "a": 1.0}}
+			rev, err := popExpectedRevision(p)
+			So(err, ShouldBeNil)
+			So(rev, ShouldEqual, 3)
+			So(p.data, ShouldResemble, map[string]interface{}{"a": 1.0})
+		})
+		Convey("missing or null revision is not checked", func() {
+			rev, err := popExpectedRevision(&dataObjectPayload{data: map[string]interface{}{}})
+			So(err, ShouldBeNil)
+			So(rev, ShouldEqual, 0)
+
+			rev, err = popExpectedRevision(&dataObjectPayload{data: map[string]interface{}{expectedRevisionKey: nil}})
+			So(err, ShouldBeNil)
+			So(rev, ShouldEqual, 0)
+		})
+		Convey("invalid revision results in error", func() {
+			_, err := popExpectedRevision(&dataObjectPayload{data: map[string]interface{}{expectedRevisionKey: "abc"}})
+			So(err, ShouldNotBeNil)
+			_, err = popExpectedRevision(&dataObjectPayload{data: map[string]interface{}{expectedRevisionKey: 0.0}})
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"fmt"
+
+	json "github.com/json-iterator/go"
+	geom "github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+const (
+	distanceOrderPrefix = "distance:"
+	maxPolygonPoints    = 256
+	metersInKilometer   = 1000
+	metersInMile        = 1609.344
+)
+
+type radiusLookup struct {
+	Longitude            *float64 `validate:"required,gte=-180,lte=180"`
+	Latitude             *float64 `validate:"required,gte=-90,lte=90"`
+	DistanceInKilometers float64  `mapstructure:"distance_in_kilometers" validate:"gte=0"`
+	DistanceInMiles      float64  `mapstructure:"distance_in_miles" validate:"gte=0"`
+}
+
+// Meters returns radius in meters.
+func (l *radiusLookup) Meters() float64 {
+	if l.DistanceInKilometers > 0 {
+		return l.DistanceInKilometers * metersInKilometer
+	}
+
+	return l.DistanceInMiles * metersInMile
+}
+
+// Point returns center of radius.
+func (l *radiusLookup) Point() *geom.Point {
+	return geom.NewPointFlat(geom.XY, []float64{*l.Longitude, *l.Latitude}).SetSRID(geopointSRID)
+}
+
+type bboxLookup struct {
+	MinLongitude *float64 `mapstructure:"min_longitude" validate:"required,gte=-180,lte=180"`
+	MinLatitude  *float64 `mapstructure:"min_latitude" validate:"required,gte=-90,lte=90"`
+	MaxLongitude *float64 `mapstructure:"max_longitude" validate:"required,gte=-180,lte=180"`
+	MaxLatitude  *float64 `mapstructure:"max_latitude" validate:"required,gte=-90,lte=90"`
+}
+
+// Valid returns true if bounding box is not empty.
+func (l *bboxLookup) Valid() bool {
+	return *l.MinLongitude < *l.MaxLongitude && *l.MinLatitude < *l.MaxLatitude
+}
+
+// Polygon returns bounding box as a closed polygon.
+func (l *bboxLookup) Polygon() *geom.Polygon {
+	minX, minY, maxX, maxY := *l.MinLongitude, *l.MinLatitude, *l.MaxLongitude, *l.MaxLatitude
+
+	return geom.NewPolygonFlat(geom.XY,
+		[]float64{minX, minY, maxX, minY, maxX, maxY, minX, maxY, minX, minY},
+		[]int{10},
+	).SetSRID(geopointSRID)
+}
+
+// geoJSONPolygon decodes GeoJSON polygon geometry. Returns nil if it is invalid.
+func geoJSONPolygon(val interface{}) *geom.Polygon {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return nil
+	}
+
+	var g geom.T
+
+	if geojson.Unmarshal(b, &g) != nil {
+		return nil
+	}
+
+	poly, ok := g.(*geom.Polygon)
+	if !ok || poly.NumCoords() < 4 || poly.NumCoords() > maxPolygonPoints {
+		return nil
+	}
+
+	coords := poly.FlatCoords()
+	for i := 0; i < len(coords); i += poly.Stride() {
+		if coords[i] < -180 || coords[i] > 180 || coords[i+1] < -90 || coords[i+1] > 90 {
+			return nil
+		}
+	}
+
+	return poly.SetSRID(geopointSRID)
+}
+
+// distanceValueSQL returns expression of distance in meters between field and point, null if field is not set.
+// Order fields and column expressions cannot carry query params so point is inlined as EWKB hex.
+func distanceValueSQL(f models.FilterField, p *geom.Point) string {
+	s, err := models.ValueToString(models.FieldGeopointType, p)
+	if err != nil {
+		panic(err)
+	}
+
+	return fmt.Sprintf("ST_Distance(%s, ST_GeomFromEWKB(decode('%s', 'hex'))::geography, false)", f.SQLName(), s)
+}
+
+// distanceSQL returns expression of distance in meters between field and point. Missing values are ordered last.
+func distanceSQL(f models.FilterField, p *geom.Point) string {
+	return fmt.Sprintf("COALESCE(%s, 'Infinity')", distanceValueSQL(f, p))
+}
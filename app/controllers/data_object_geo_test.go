@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	geom "github.com/twpayne/go-geom"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestGeoLookups(t *testing.T) {
+	Convey("Given geopoint field", t, func() {
+		doq := NewDataObjectQuery(testFilterFields(
+			&models.DataObjectField{FName: "loc", FType: models.FieldGeopointType},
+			&models.DataObjectField{FName: "other", FType: models.FieldGeopointType},
+		))
+
+		Convey("_within_radius filters by geography distance in meters", func() {
+			sql, err := testQuerySQL(doq, `{"loc": {"_within_radius": {"longitude": 10, "latitude": 20, "distance_in_kilometers": 2}}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `ST_DWithin(("t"."_data"->'_loc')::geography(POINT,4326), ST_GeomFromEWKB(`)
+			So(sql, ShouldContainSubstring, `::geography, 2000, false)`)
+		})
+		Convey("_within_radius accepts distance in miles", func() {
+			l := &radiusLookup{DistanceInMiles: 2}
+			So(l.Meters(), ShouldEqual, 2*metersInMile)
+		})
+		Convey("_within_radius requires center and positive radius", func() {
+			_, err := testQuerySQL(doq, `{"loc": {"_within_radius": {"longitude": 10, "distance_in_kilometers": 2}}}`)
+			So(err, ShouldNotBeNil)
+			_, err = testQuerySQL(doq, `{"loc": {"_within_radius": {"longitude": 10, "latitude": 20}}}`)
+			So(err, ShouldNotBeNil)
+			_, err = testQuerySQL(doq, `{"loc": {"_within_radius": {"longitude": 190, "latitude": 20, "distance_in_miles": 1}}}`)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("_within_radius of zero coordinates is valid", func() {
+			_, err := testQuerySQL(doq, `{"loc": {"_within_radius": {"longitude": 0, "latitude": 0, "distance_in_miles": 1}}}`)
+			So(err, ShouldBeNil)
+		})
+		Convey("_within_bbox requires non empty box", func() {
+			sql, err := testQuerySQL(doq,
+				`{"loc": {"_within_bbox": {"min_longitude": 1, "min_latitude": 2, "max_longitude": 3, "max_latitude": 4}}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_loc')::geography(POINT,4326)::geometry && ST_GeomFromEWKB(`)
+
+			_, err = testQuerySQL(doq,
+				`{"loc": {"_within_bbox": {"min_longitude": 3, "min_latitude": 2, "max_longitude": 1, "max_latitude": 4}}}`)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("_within_polygon requires valid GeoJSON polygon", func() {
+			sql, err := testQuerySQL(doq,
+				`{"loc": {"_within_polygon": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `ST_Covers(ST_GeomFromEWKB(`)
+
+			_, err = testQuerySQL(doq, `{"loc": {"_within_polygon": {"type": "Point", "coordinates": [0, 0]}}}`)
+			So(err, ShouldNotBeNil)
+			_, err = testQuerySQL(doq,
+				`{"loc": {"_within_polygon": {"type": "Polygon", "coordinates": [[[0, 0], [200, 0], [1, 1], [0, 0]]]}}}`)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("_within_radius tracks distance for ordering and response", func() {
+			_, err := testQuerySQL(doq, `{"loc": {"_within_radius": {"longitude": 10, "latitude": 20, "distance_in_miles": 1}}}`)
+			So(err, ShouldBeNil)
+
+			center, err := models.ValueToString(models.FieldGeopointType,
+				geom.NewPointFlat(geom.XY, []float64{10, 20}).SetSRID(geopointSRID))
+			So(err, ShouldBeNil)
+
+			distance := `ST_Distance(("t"."_data"->'_loc')::geography(POINT,4326), ST_GeomFromEWKB(decode('` + center +
+				`', 'hex'))::geography, false)`
+
+			order := doq.OrderFields(&models.Class{ID: 1, Name: "cls"})
+			So(order, ShouldContainKey, distanceOrderPrefix+"loc")
+			So(order[distanceOrderPrefix+"loc"].SQLName(), ShouldEqual, "COALESCE("+distance+", 'Infinity')")
+			So(doq.DistancesExpr(), ShouldEqual, "json_build_object('loc', "+distance+")")
+		})
+		Convey("_within_radius in _or and _not is not tracked", func() {
+			_, err := testQuerySQL(doq, `{"_or": [{"loc": {"_within_radius": {"longitude": 10, "latitude": 20, "distance_in_miles": 1}}}], `+
+				`"_not": [{"other": {"_within_radius": {"longitude": 10, "latitude": 20, "distance_in_miles": 1}}}]}`)
+			So(err, ShouldBeNil)
+			So(doq.DistancesExpr(), ShouldBeEmpty)
+			So(doq.OrderFields(&models.Class{ID: 1, Name: "cls"}), ShouldBeEmpty)
+		})
+		Convey("_within_radius in _and is tracked", func() {
+			_, err := testQuerySQL(doq, `{"_and": [{"loc": {"_within_radius": {"longitude": 10, "latitude": 20, "distance_in_miles": 1}}}]}`)
+			So(err, ShouldBeNil)
+			So(doq.OrderFields(&models.Class{ID: 1, Name: "cls"}), ShouldContainKey, distanceOrderPrefix+"loc")
+		})
+		Convey("ordering by distance without tracked _within_radius results in error", func() {
+			p := &PaginatorOrderedDB{OrderFields: doq.OrderFields(&models.Class{ID: 1, Name: "cls"})}
+			cur := testKeysetCursor(p, url.Values{"order_by": {"-" + distanceOrderPrefix + "loc"}})
+			So(cur.err, ShouldResemble, missingOrderFieldError(distanceOrderPrefix+"loc"))
+			So(cur.err.(*api.Error).Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("query without radius lookups selects no distances", func() {
+			_, err := testQuerySQL(doq,
+				`{"loc": {"_within_bbox": {"min_longitude": 1, "min_latitude": 2, "max_longitude": 3, "max_latitude": 4}}}`)
+			So(err, ShouldBeNil)
+			So(doq.DistancesExpr(), ShouldBeEmpty)
+		})
+	})
+}
@@ -0,0 +1,547 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v9"
+	"github.com/go-redis/redis/v7"
+	"github.com/jackc/pgtype"
+	json "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/serializers"
+	"github.com/Syncano/orion/app/settings"
+	"github.com/Syncano/pkg-go/v2/redisdb"
+	"github.com/Syncano/pkg-go/v2/util"
+)
+
+const (
+	importFileField      = "file"
+	importChunkSize      = 500
+	importErrorsMax      = 100
+	importRunningKeyTmpl = "%d:import:running"
+)
+
+// importContextKeys are copied from request context to context of import that outlives it.
+var importContextKeys = []string{
+	settings.ContextInstanceKey,
+	settings.ContextInstanceOwnerKey,
+	settings.ContextSchemaKey,
+	settings.ContextAdminKey,
+	settings.ContextAPIKeyKey,
+	settings.ContextUserKey,
+	contextSubscriptionKey,
+	contextAdminLimitKey,
+	contextClassKey,
+}
+
+// importRowError is an error of single row of import file that does not stop the import.
+type importRowError string
+
+func (e importRowError) Error() string {
+	return string(e)
+}
+
+// importRow is a row of import file with its number.
+type importRow struct {
+	num  int
+	data map[string]interface{}
+}
+
+// importReader returns next row of import file or io.EOF when there are no more rows.
+type importReader func() (map[string]interface{}, error)
+
+func newNDJSONImportReader(r io.Reader) importReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 2*settings.API.DataObjectMaxSize)
+
+	return func() (map[string]interface{}, error) {
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(strings.TrimSpace(string(line))) == 0 {
+				continue
+			}
+
+			var m map[string]interface{}
+			if err := json.Unmarshal(line, &m); err != nil {
+				return nil, importRowError("Invalid JSON object.")
+			}
+
+			return m, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			if err == bufio.ErrTooLong {
+				return nil, api.NewBadRequestError(fmt.Sprintf("Row size exceeds the limit (%d bytes).", 2*settings.API.DataObjectMaxSize))
+			}
+
+			return nil, err
+		}
+
+		return nil, io.EOF
+	}
+}
+
+func newCSVImportReader(r io.Reader) (importReader, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, api.NewBadRequestError("Missing CSV header.")
+		}
+
+		return nil, api.NewBadRequestError("Invalid CSV header.")
+	}
+
+	return func() (map[string]interface{}, error) {
+		record, err := reader.Read()
+		if err != nil {
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				return nil, importRowError(pe.Err.Error())
+			}
+
+			return nil, err
+		}
+
+		// Empty cells are treated as missing values.
+		m := make(map[string]interface{}, len(header))
+
+		for i, val := range record {
+			if val != "" {
+				m[header[i]] = val
+			}
+		}
+
+		return m, nil
+	}, nil
+}
+
+func (ctr *Controller) createDataObjectImportDBCtx(c echo.Context, o interface{}) *redisdb.DBCtx {
+	return ctr.redis.DB().Model(o, map[string]interface{}{
+		"instance": c.Get(settings.ContextInstanceKey).(*models.Instance),
+		"class":    c.Get(contextClassKey).(*models.Class),
+	})
+}
+
+// DataObjectImportCreate schedules import of data objects from uploaded NDJSON or CSV file.
+func (ctr *Controller) DataObjectImportCreate(c echo.Context) error {
+	fh, err := c.FormFile(importFileField)
+	if err != nil {
+		return api.NewError(http.StatusBadRequest, map[string]interface{}{importFileField: "No file was submitted."})
+	}
+
+	format := c.FormValue(exportFormatQuery)
+	if format == "" {
+		format = exportFormatNDJSON
+
+		if strings.EqualFold(filepath.Ext(fh.Filename), "."+exportFormatCSV) {
+			format = exportFormatCSV
+		}
+	}
+
+	if _, ok := exportContentTypes[format]; !ok {
+		return newFormatError()
+	}
+
+	sub := c.Get(contextSubscriptionKey).(*models.Subscription)
+
+	rowsLimit := c.Get(contextAdminLimitKey).(*models.AdminLimit).ImportRowsCount(sub)
+	if rowsLimit == 0 {
+		return api.NewGenericError(http.StatusForbidden, "Data object import is not available in current plan.")
+	}
+
+	if err := ctr.checkStorageLimit(c, fh.Size); err != nil {
+		return err
+	}
+
+	token := util.GenerateHexKey()
+
+	if ok, err := ctr.acquireImportSlot(c, token); err != nil {
+		return err
+	} else if !ok {
+		return api.NewGenericError(http.StatusTooManyRequests,
+			fmt.Sprintf("Too many imports running (exceeds %d).", settings.API.DataObjectImportsMax))
+	}
+
+	// Uploaded file is removed after request so it needs to be copied.
+	path, err := saveImportFile(fh)
+	if err != nil {
+		ctr.releaseImportSlot(c, token)
+		return err
+	}
+
+	now := time.Now()
+	o := &models.DataObjectImport{Format: format, CreatedAt: now, HeartbeatAt: now}
+
+	if err := ctr.createDataObjectImportDBCtx(c, o).Save(nil); err != nil {
+		ctr.releaseImportSlot(c, token)
+		os.Remove(path) // nolint: errcheck
+
+		return err
+	}
+
+	job := *o
+	go ctr.runDataObjectImport(detachContext(c, importContextKeys...), &job, token, path, rowsLimit)
+
+	return api.Render(c, http.StatusAccepted, serializers.DataObjectImportSerializer{}.Response(o))
+}
+
+func (ctr *Controller) DataObjectImportList(c echo.Context) error {
+	var o []*models.DataObjectImport
+
+	paginator := &PaginatorRedis{DBCtx: ctr.createDataObjectImportDBCtx(c, &o), SkippedFields: []string{"errors"}}
+	cursor := paginator.CreateCursor(c, false)
+
+	r, err := Paginate(c, cursor, (*models.DataObjectImport)(nil), serializers.DataObjectImportSerializer{}, paginator)
+	if err != nil {
+		return err
+	}
+
+	return api.Render(c, http.StatusOK, serializers.CreatePage(c, r, nil))
+}
+
+func (ctr *Controller) DataObjectImportRetrieve(c echo.Context) error {
+	o := &models.DataObjectImport{}
+
+	v, ok := api.IntGet(c, "import_id")
+	if !ok {
+		return api.NewNotFoundError(o)
+	}
+
+	if err := ctr.createDataObjectImportDBCtx(c, o).Find(v); err != nil {
+		if err == redisdb.ErrNotFound {
+			return api.NewNotFoundError(o)
+		}
+
+		return err
+	}
+
+	return api.Render(c, http.StatusOK, serializers.DataObjectImportSerializer{}.Response(o))
+}
+
+// detachContext returns new context with values of specified keys that can be used after request is finished.
+func detachContext(c echo.Context, keys ...string) echo.Context {
+	ctx := c.Echo().NewContext(c.Request().Clone(context.Background()), nil)
+
+	for _, k := range keys {
+		ctx.Set(k, c.Get(k))
+	}
+
+	return ctx
+}
+
+func saveImportFile(fh *multipart.FileHeader) (string, error) {
+	src, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+
+	defer src.Close()
+
+	dst, err := ioutil.TempFile("", "import-")
+	if err != nil {
+		return "", err
+	}
+
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		os.Remove(dst.Name()) // nolint: errcheck
+		return "", err
+	}
+
+	return dst.Name(), nil
+}
+
+// importRunningKey returns key of sorted set of imports running in instance scored by their last heartbeat.
+func importRunningKey(c echo.Context) string {
+	return fmt.Sprintf(importRunningKeyTmpl, c.Get(settings.ContextInstanceKey).(*models.Instance).ID)
+}
+
+// acquireImportSlot registers import identified by token as running in instance. Returns false if there are
+// too many imports running already. Imports without heartbeat for longer than stale timeout are not counted.
+func (ctr *Controller) acquireImportSlot(c echo.Context, token string) (bool, error) {
+	key := importRunningKey(c)
+	now := time.Now()
+
+	var count *redis.IntCmd
+
+	if _, err := ctr.redis.Client().TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(key, "-inf", strconv.FormatInt(now.Add(-models.DataObjectImportStaleTimeout).Unix(), 10))
+		pipe.ZAdd(key, &redis.Z{Score: float64(now.Unix()), Member: token})
+		count = pipe.ZCard(key)
+		pipe.Expire(key, models.DataObjectImportStaleTimeout)
+
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	if count.Val() > int64(settings.API.DataObjectImportsMax) {
+		ctr.releaseImportSlot(c, token)
+		return false, nil
+	}
+
+	return true, nil
+}
+
+func (ctr *Controller) releaseImportSlot(c echo.Context, token string) {
+	ctr.redis.Client().ZRem(importRunningKey(c), token)
+}
+
+// saveImportProgress saves import along with heartbeat of its slot so that it is not considered interrupted.
+func (ctr *Controller) saveImportProgress(c echo.Context, dbCtx *redisdb.DBCtx, o *models.DataObjectImport, token string) error {
+	key := importRunningKey(c)
+	o.HeartbeatAt = time.Now()
+
+	pipe := ctr.redis.Client().TxPipeline()
+	pipe.ZAdd(key, &redis.Z{Score: float64(o.HeartbeatAt.Unix()), Member: token})
+	pipe.Expire(key, models.DataObjectImportStaleTimeout)
+
+	if _, err := pipe.Exec(); err != nil {
+		return err
+	}
+
+	return dbCtx.Save(nil)
+}
+
+// runDataObjectImport processes import and saves its final status. Triggers are not launched for imported objects.
+func (ctr *Controller) runDataObjectImport(c echo.Context, o *models.DataObjectImport, token, path string, rowsLimit int) {
+	defer os.Remove(path) // nolint: errcheck
+	defer ctr.releaseImportSlot(c, token)
+
+	dbCtx := ctr.createDataObjectImportDBCtx(c, o)
+	o.Status = models.DataObjectImportStatusProcessing
+	o.Errors = make(map[string]interface{})
+
+	defer func() {
+		if r := recover(); r != nil {
+			ctr.log.Logger().With(zap.Any("panic", r)).Error("Data object import panicked")
+			ctr.finishDataObjectImport(dbCtx, o, api.ErrInternal)
+		}
+	}()
+
+	save := func() error {
+		return ctr.saveImportProgress(c, dbCtx, o, token)
+	}
+
+	err := save()
+	if err == nil {
+		err = ctr.importDataObjects(c, o, path, rowsLimit, save)
+	}
+
+	ctr.finishDataObjectImport(dbCtx, o, err)
+}
+
+// finishDataObjectImport saves final status of import depending on its error.
+func (ctr *Controller) finishDataObjectImport(dbCtx *redisdb.DBCtx, o *models.DataObjectImport, err error) {
+	o.Status = models.DataObjectImportStatusSuccess
+	o.FinishedAt = time.Now()
+
+	if err != nil {
+		var e *api.Error
+
+		o.Status = models.DataObjectImportStatusFailure
+
+		if errors.As(err, &e) {
+			o.Detail = e.Error()
+		} else {
+			o.Detail = api.ErrInternal.Error()
+
+			ctr.log.Logger().With(zap.Error(err)).Error("Data object import failed")
+		}
+	}
+
+	if err := dbCtx.Save(nil); err != nil {
+		ctr.log.Logger().With(zap.Error(err)).Error("Saving data object import failed")
+	}
+}
+
+// importDataObjects reads rows of import file and inserts them in chunks, saving progress after each of them.
+// Rows that are malformed or fail validation against class schema are skipped and reported in import errors.
+func (ctr *Controller) importDataObjects(c echo.Context, o *models.DataObjectImport, path string, rowsLimit int, save func() error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	var next importReader
+
+	if o.Format == exportFormatCSV {
+		if next, err = newCSVImportReader(f); err != nil {
+			return err
+		}
+	} else {
+		next = newNDJSONImportReader(f)
+	}
+
+	rows := make([]*importRow, 0, importChunkSize)
+
+	for {
+		data, err := next()
+		if err == io.EOF {
+			break
+		}
+
+		o.Processed++
+
+		if rowsLimit > 0 && o.Processed > rowsLimit {
+			return api.NewBadRequestError(fmt.Sprintf("Number of rows exceeds the limit (%d).", rowsLimit))
+		}
+
+		var re importRowError
+
+		switch {
+		case errors.As(err, &re):
+			addImportError(o, o.Processed, re.Error())
+			continue
+		case err != nil:
+			return err
+		}
+
+		rows = append(rows, &importRow{num: o.Processed, data: data})
+
+		if len(rows) < importChunkSize {
+			continue
+		}
+
+		if err := ctr.importDataObjectsChunk(c, o, rows); err != nil {
+			return err
+		}
+
+		rows = rows[:0]
+
+		if err := save(); err != nil {
+			return err
+		}
+	}
+
+	return ctr.importDataObjectsChunk(c, o, rows)
+}
+
+// importDataObjectsChunk validates rows and inserts valid ones with one multi-row insert.
+// Storage size of instance is increased by size of imported objects if it does not exceed storage limit.
+func (ctr *Controller) importDataObjectsChunk(c echo.Context, o *models.DataObjectImport, rows []*importRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	class := c.Get(contextClassKey).(*models.Class)
+	mgr := ctr.q.NewDataObjectManager(c)
+	objs := make([]*models.DataObject, 0, len(rows))
+
+	if err := mgr.RunInTransaction(func(tx *pg.Tx) error {
+		unique := make(importUniqueValues)
+		size := 0
+
+		for _, row := range rows {
+			obj := models.NewDataObject(class)
+			setDataObjectOwner(c, obj)
+
+			err := ctr.bindDataObject(c, tx, class, obj, &dataObjectPayload{data: row.data})
+			if err == nil {
+				err = unique.add(class, obj)
+			}
+
+			if err != nil {
+				var e *api.Error
+				if errors.As(err, &e) && e.Code == http.StatusBadRequest {
+					addImportError(o, row.num, e.Data)
+					continue
+				}
+
+				return err
+			}
+
+			objs = append(objs, obj)
+			size += dataObjectSize(obj)
+		}
+
+		if len(objs) == 0 {
+			return nil
+		}
+
+		if err := ctr.checkStorageLimit(c, int64(size)); err != nil {
+			return err
+		}
+
+		if err := mgr.Insert(&objs); err != nil {
+			return err
+		}
+
+		return ctr.updateInstanceIndicatorValue(c, tx, models.InstanceIndicatorTypeStorageSize, size)
+	}); err != nil {
+		return uniqueViolationError(class, err)
+	}
+
+	o.Imported += len(objs)
+
+	return nil
+}
+
+// importUniqueValues tracks values of unique fields used by rows of chunk. They are not inserted yet
+// so they are not visible to unique check of data object.
+type importUniqueValues map[string]map[string]struct{}
+
+// add returns error if values of unique fields of data object were already used by previous rows of chunk.
+// Otherwise values are tracked.
+func (u importUniqueValues) add(class *models.Class, o *models.DataObject) error {
+	errs := make(map[string]interface{})
+	values := make(map[string]string)
+
+	for name, f := range class.ComputedSchema() {
+		v := o.Data.Map[f.Mapping]
+		if !f.Unique || v.Status != pgtype.Present {
+			continue
+		}
+
+		if _, ok := u[name][v.String]; ok {
+			errs[name] = []string{errFieldUnique.Error()}
+			continue
+		}
+
+		values[name] = v.String
+	}
+
+	if len(errs) > 0 {
+		return api.NewError(http.StatusBadRequest, errs)
+	}
+
+	for name, v := range values {
+		if u[name] == nil {
+			u[name] = make(map[string]struct{})
+		}
+
+		u[name][v] = struct{}{}
+	}
+
+	return nil
+}
+
+func addImportError(o *models.DataObjectImport, row int, detail interface{}) {
+	o.Failed++
+
+	if len(o.Errors) < importErrorsMax {
+		o.Errors[strconv.Itoa(row)] = detail
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestImportReaders(t *testing.T) {
+	Convey("Given NDJSON import file", t, func() {
+		next := newNDJSONImportReader(strings.NewReader("{\"a\": 1}\n\n  \nnot json\n{\"b\": \"x\"}\n"))
+
+		Convey("rows are read skipping empty lines and reporting invalid ones", func() {
+			m, err := next()
+			So(err, ShouldBeNil)
+			So(m, ShouldResemble, map[string]interface{}{"a": 1.0})
+
+			_, err = next()
+			So(err, ShouldHaveSameTypeAs, importRowError(""))
+
+			m, err = next()
+			So(err, ShouldBeNil)
+			So(m, ShouldResemble, map[string]interface{}{"b": "x"})
+
+			_, err = next()
+			So(err, ShouldEqual, io.EOF)
+		})
+	})
+
+	Convey("Given CSV import file", t, func() {
+		Convey("rows are mapped by header skipping empty cells", func() {
+			next, err := newCSVImportReader(strings.NewReader("a,b\n1,\n\"x,y\",2\n"))
+			So(err, ShouldBeNil)
+
+			m, err := next()
+			So(err, ShouldBeNil)
+			So(m, ShouldResemble, map[string]interface{}{"a": "1"})
+
+			m, err = next()
+			So(err, ShouldBeNil)
+			So(m, ShouldResemble, map[string]interface{}{"a": "x,y", "b": "2"})
+
+			_, err = next()
+			So(err, ShouldEqual, io.EOF)
+		})
+		Convey("malformed rows are reported as row errors", func() {
+			next, err := newCSVImportReader(strings.NewReader("a,b\n1,2,3\n"))
+			So(err, ShouldBeNil)
+
+			_, err = next()
+			So(err, ShouldHaveSameTypeAs, importRowError(""))
+		})
+		Convey("missing header results in error", func() {
+			_, err := newCSVImportReader(strings.NewReader(""))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestImportUniqueValues(t *testing.T) {
+	Convey("Given chunk of imported objects", t, func() {
+		class := models.NewClass()
+		class.Name = "cls"
+		So(class.SetSchema([]map[string]interface{}{
+			{"name": "u", "type": models.FieldStringType, "unique": true},
+			{"name": "s", "type": models.FieldStringType},
+		}, map[string]string{"u": "_u", "s": "_s"}), ShouldBeNil)
+
+		schema := class.ComputedSchema()
+		newObject := func(u, s interface{}) *models.DataObject {
+			o := models.NewDataObject(class)
+			So(schema["u"].Set(o.Data, u), ShouldBeNil)
+			So(schema["s"].Set(o.Data, s), ShouldBeNil)
+
+			return o
+		}
+		unique := make(importUniqueValues)
+
+		Convey("rows reusing unique values of previous rows are rejected", func() {
+			So(unique.add(class, newObject("a", "x")), ShouldBeNil)
+			So(unique.add(class, newObject("b", "x")), ShouldBeNil)
+
+			err := unique.add(class, newObject("a", "y"))
+			So(err, ShouldNotBeNil)
+			So(err.(*api.Error).Data, ShouldContainKey, "u")
+		})
+		Convey("null values are not tracked", func() {
+			So(unique.add(class, newObject(nil, "x")), ShouldBeNil)
+			So(unique.add(class, newObject(nil, "x")), ShouldBeNil)
+		})
+	})
+}
+
+func TestAddImportError(t *testing.T) {
+	Convey("Import errors are counted and limited", t, func() {
+		o := &models.DataObjectImport{Errors: make(map[string]interface{})}
+
+		for i := 1; i <= importErrorsMax+5; i++ {
+			addImportError(o, i, "error")
+		}
+
+		So(o.Failed, ShouldEqual, importErrorsMax+5)
+		So(o.Errors, ShouldHaveLength, importErrorsMax)
+		So(o.Errors, ShouldContainKey, strconv.Itoa(1))
+	})
+}
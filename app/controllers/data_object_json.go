@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-pg/pg/v9/orm"
+	json "github.com/json-iterator/go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/query"
+)
+
+const (
+	// fieldJSONPathType is a type of virtual filter field pointing at nested value of object field.
+	fieldJSONPathType = "json_path"
+	jsonPathMaxDepth  = 8
+)
+
+var jsonPathKeyRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// jsonPathField is a filter field of value nested in object field. Values are compared as jsonb.
+type jsonPathField struct {
+	parent models.FilterField
+	path   []string
+}
+
+func (f *jsonPathField) Name() string {
+	return f.parent.Name() + "." + strings.Join(f.path, ".")
+}
+
+func (f *jsonPathField) Type() string {
+	return fieldJSONPathType
+}
+
+// SQLName returns path expression. Path keys are validated so they are safe to inline.
+func (f *jsonPathField) SQLName() string {
+	return fmt.Sprintf("(%s #> '{%s}')", f.parent.SQLName(), strings.Join(f.path, ","))
+}
+
+// jsonValue returns JSON representation of lookup value.
+func jsonValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(b)
+}
+
+// jsonPathQuery is a nested query map applied on paths of object field.
+type jsonPathQuery struct {
+	doq *DataObjectQuery
+	m   map[string]interface{}
+}
+
+func newJSONPathQuery(c echo.Context, qf *query.Factory, f models.FilterField, m map[string]interface{}) (*jsonPathQuery, error) {
+	fields := make(map[string]models.FilterField, len(m))
+
+	for key := range m {
+		if _, ok := logicalOperators[key]; ok {
+			return nil, newQueryError(fmt.Sprintf(`Logical operators are not supported in "_path" lookup of field "%s".`, f.Name()))
+		}
+
+		path := strings.Split(key, ".")
+		if len(path) > jsonPathMaxDepth {
+			return nil, newQueryError(fmt.Sprintf(`Too deeply nested path "%s" (exceeds %d).`, key, jsonPathMaxDepth))
+		}
+
+		for _, k := range path {
+			if !jsonPathKeyRegex.MatchString(k) {
+				return nil, newQueryError(fmt.Sprintf(`Invalid path specified: "%s".`, key))
+			}
+		}
+
+		fields[key] = &jsonPathField{parent: f, path: path}
+	}
+
+	doq := NewDataObjectQuery(fields)
+	if err := doq.Validate(m, false); err != nil {
+		return nil, err
+	}
+
+	// Process nested lookups once on detached query so that their errors are reported during validation.
+	if _, err := doq.ParseMap(c, qf, orm.NewQuery(nil), m); err != nil {
+		return nil, err
+	}
+
+	return &jsonPathQuery{doq: doq, m: m}, nil
+}
+
+func (jq *jsonPathQuery) apply(c echo.Context, qf *query.Factory, q *orm.Query) *orm.Query {
+	return q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+		return jq.doq.ParseMap(c, qf, q, jq.m)
+	})
+}
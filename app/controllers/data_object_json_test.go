@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestArrayLookups(t *testing.T) {
+	Convey("Given array field", t, func() {
+		doq := NewDataObjectQuery(testFilterFields(
+			&models.DataObjectField{FName: "arr", FType: models.FieldArrayType},
+			&models.DataObjectField{FName: "s", FType: models.FieldStringType},
+		))
+		field := `("t"."_data"->'_arr')::jsonb`
+
+		Convey("_contained_by compares with JSON array", func() {
+			sql, err := testQuerySQL(doq, `{"arr": {"_contained_by": ["a", 1, true]}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, field+` <@ '["a",1,true]'`)
+		})
+		Convey("_overlaps matches any of values", func() {
+			sql, err := testQuerySQL(doq, `{"arr": {"_overlaps": ["a", 1]}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, field+` @> '["a"]' OR `+field+` @> '[1]'`)
+		})
+		Convey("_length_gt and _length_lt compare array length", func() {
+			sql, err := testQuerySQL(doq, `{"arr": {"_length_gt": 2, "_length_lt": 5}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, "jsonb_array_length("+field+") > 2")
+			So(sql, ShouldContainSubstring, "jsonb_array_length("+field+") < 5")
+		})
+		Convey("invalid values are rejected", func() {
+			for _, q := range []string{
+				`{"arr": {"_contained_by": []}}`,
+				`{"arr": {"_overlaps": [{"a": 1}]}}`,
+				`{"arr": {"_length_gt": "2"}}`,
+				`{"arr": {"_length_gt": 1.5}}`,
+				`{"arr": {"_length_lt": 1e12}}`,
+			} {
+				_, err := testQuerySQL(doq, q)
+				So(err, ShouldNotBeNil)
+			}
+		})
+		Convey("non integral length is rejected instead of truncated", func() {
+			_, err := testQuerySQL(doq, `{"arr": {"_length_lt": 2.5}}`)
+			So(err, ShouldResemble, newQueryError(`Invalid value type provided for "_length_lt" lookup of field "arr".`))
+		})
+		Convey("lookups are not supported for non array fields", func() {
+			_, err := testQuerySQL(doq, `{"s": {"_overlaps": ["a"]}}`)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestObjectPathLookup(t *testing.T) {
+	Convey("Given object field", t, func() {
+		doq := NewDataObjectQuery(testFilterFields(
+			&models.DataObjectField{FName: "meta", FType: models.FieldObjectType},
+			&models.DataObjectField{FName: "arr", FType: models.FieldArrayType},
+		))
+		field := `("t"."_data"->'_meta')::jsonb`
+
+		Convey("_path applies nested lookups on JSON paths", func() {
+			sql, err := testQuerySQL(doq, `{"meta": {"_path": {"color": {"_eq": "red"}, "size.w": {"_gt": 3}}}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `(`+field+` #> '{color}') = '"red"'`)
+			So(sql, ShouldContainSubstring, `(`+field+` #> '{size,w}') > '3'`)
+		})
+		Convey("_path supports containers", func() {
+			sql, err := testQuerySQL(doq, `{"meta": {"_path": {"tags": {"_contains": ["a"]}, "color": {"_in": ["red", "blue"]}}}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `(`+field+` #> '{tags}') @> '["a"]'`)
+			So(sql, ShouldContainSubstring, `(`+field+` #> '{color}') IN ('"red"','"blue"')`)
+		})
+		Convey("_path rejects invalid paths and lookups", func() {
+			for _, q := range []string{
+				`{"meta": {"_path": {}}}`,
+				`{"meta": {"_path": {"a b": {"_eq": 1}}}}`,
+				`{"meta": {"_path": {"a..b": {"_eq": 1}}}}`,
+				`{"meta": {"_path": {"` + strings.Repeat("a.", jsonPathMaxDepth) + `a": {"_eq": 1}}}}`,
+				`{"meta": {"_path": {"_or": [{"a": {"_eq": 1}}]}}}`,
+				`{"meta": {"_path": {"a": {"_search": "x"}}}}`,
+				`{"meta": {"_path": {"a": {"_eq": [1]}}}}`,
+				`{"arr": {"_path": {"a": {"_eq": 1}}}}`,
+			} {
+				_, err := testQuerySQL(doq, q)
+				So(err, ShouldNotBeNil)
+			}
+		})
+	})
+}
+
+func TestLookupIndexTypes(t *testing.T) {
+	Convey("Index type hints follow lookups supported for field type", t, func() {
+		So(lookupIndexTypes(models.FieldArrayType), ShouldContain, indexTypeGIN)
+		So(lookupIndexTypes(models.FieldObjectType), ShouldContain, indexTypeGIN)
+		So(lookupIndexTypes(models.FieldGeopointType), ShouldContain, indexTypeGIST)
+		So(lookupIndexTypes(models.FieldBooleanType), ShouldBeEmpty)
+	})
+}
@@ -3,10 +3,13 @@ package controllers
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
 	"github.com/go-pg/pg/v9/orm"
 	json "github.com/json-iterator/go"
 	"github.com/labstack/echo/v4"
+	geom "github.com/twpayne/go-geom"
 
 	"github.com/Syncano/orion/app/api"
 	"github.com/Syncano/orion/app/models"
@@ -14,16 +17,74 @@ import (
 	"github.com/Syncano/orion/app/settings"
 )
 
+// Logical operators of query.
+const (
+	queryOrOperator  = "_or"
+	queryAndOperator = "_and"
+	queryNotOperator = "_not"
+)
+
+var logicalOperators = map[string]struct{}{
+	queryOrOperator:  {},
+	queryAndOperator: {},
+	queryNotOperator: {},
+}
+
 func newQueryError(detail string) *api.Error {
 	return api.NewError(http.StatusBadRequest, map[string]interface{}{"query": detail})
 }
 
 type DataObjectQuery struct {
-	fields map[string]models.FilterField
+	fields    map[string]models.FilterField
+	ranks     []string
+	distances map[string]*geom.Point
+
+	// untracked is a number of "_or" and "_not" operators enclosing lookup being parsed.
+	// Objects matched by query do not have to match such lookups, so their ranks and distances are not tracked.
+	untracked int
 }
 
 func NewDataObjectQuery(fields map[string]models.FilterField) *DataObjectQuery {
-	return &DataObjectQuery{fields: fields}
+	return &DataObjectQuery{fields: fields, distances: make(map[string]*geom.Point)}
+}
+
+// OrderFields returns order fields computed from parsed lookups: "_rank" of full text search
+// and "distance:<field>" of geo radius lookups.
+func (doq *DataObjectQuery) OrderFields(class *models.Class) map[string]models.OrderField {
+	ret := make(map[string]models.OrderField)
+
+	if len(doq.ranks) > 0 {
+		// Rank is negated so that ascending order returns most relevant objects first.
+		ret[rankOrderField] = newComputedOrderField(class, fmt.Sprintf("-(%s)", strings.Join(doq.ranks, " + ")))
+	}
+
+	for name, p := range doq.distances {
+		ret[distanceOrderPrefix+name] = newComputedOrderField(class, distanceSQL(doq.fields[name], p))
+	}
+
+	return ret
+}
+
+// DistancesExpr returns expression of JSON object with distances in meters between geopoint fields and centers
+// of their radius lookups. Returns empty string if query has no radius lookups.
+func (doq *DataObjectQuery) DistancesExpr() string {
+	if len(doq.distances) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(doq.distances))
+	for name := range doq.distances {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	args := make([]string, len(names))
+	for i, name := range names {
+		args[i] = fmt.Sprintf("'%s', %s", name, distanceValueSQL(doq.fields[name], doq.distances[name]))
+	}
+
+	return fmt.Sprintf("json_build_object(%s)", strings.Join(args, ", "))
 }
 
 func (doq *DataObjectQuery) Parse(qf *query.Factory, c echo.Context, q *orm.Query) (*orm.Query, error) {
@@ -55,6 +116,14 @@ func (doq *DataObjectQuery) ParseMap(c echo.Context, qf *query.Factory, q *orm.Q
 	)
 
 	for name, props := range m {
+		if _, ok = logicalOperators[name]; ok {
+			if q, err = doq.logicalQuery(c, qf, q, name, props.([]interface{})); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
 		f, ok = doq.fields[name]
 		if !ok {
 			return nil, newQueryError(fmt.Sprintf(`Invalid field name specified or missing filter index: "%s".`, name))
@@ -70,34 +139,123 @@ func (doq *DataObjectQuery) ParseMap(c echo.Context, qf *query.Factory, q *orm.Q
 	return q, nil
 }
 
-func (doq *DataObjectQuery) Validate(m map[string]interface{}, top bool) error {
-	var (
-		ok        bool
-		propMap   map[string]interface{}
-		nestCount int
-	)
+// tracksLookups returns true if ranks and distances of lookup being parsed should be tracked.
+// Only lookups at top level of query or nested in "_and" are tracked.
+func (doq *DataObjectQuery) tracksLookups() bool {
+	return doq.untracked == 0
+}
 
-	for name, props := range m {
-		propMap, ok = props.(map[string]interface{})
-		if !ok {
-			return newQueryError(fmt.Sprintf(`Expected dict at "%s".`, name))
+// logicalQuery adds grouped WHERE clause composed of nested query maps.
+func (doq *DataObjectQuery) logicalQuery(c echo.Context, qf *query.Factory, q *orm.Query, op string, lst []interface{}) (*orm.Query, error) {
+	var err error
+
+	if op != queryAndOperator {
+		doq.untracked++
+		defer func() { doq.untracked-- }()
+	}
+
+	q = q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+		// Start with TRUE so that leading NOT separator is never dropped.
+		if op == queryNotOperator {
+			q = q.Where("TRUE")
 		}
 
-		if _, ok = propMap["_is"]; ok {
-			nestCount++
+		for _, item := range lst {
+			m := item.(map[string]interface{})
+			group := func(q *orm.Query) (*orm.Query, error) {
+				nq, e := doq.ParseMap(c, qf, q, m)
+				if e != nil {
+					err = e
+					return q, nil
+				}
+
+				return nq, nil
+			}
+
+			switch op {
+			case queryOrOperator:
+				q = q.WhereOrGroup(group)
+			case queryAndOperator:
+				q = q.WhereGroup(group)
+			case queryNotOperator:
+				q = q.WhereNotGroup(group)
+			}
 		}
+
+		return q, nil
+	})
+
+	return q, err
+}
+
+// queryStats holds statistics of query map used to enforce query limits.
+type queryStats struct {
+	nested  int
+	clauses int
+}
+
+func (doq *DataObjectQuery) Validate(m map[string]interface{}, top bool) error {
+	st := &queryStats{}
+
+	if err := doq.validateMap(m, 0, st); err != nil {
+		return err
 	}
 
 	if top {
-		if nestCount > settings.API.DataObjectNestedQueriesMax {
+		if st.nested > settings.API.DataObjectNestedQueriesMax {
 			return newQueryError(fmt.Sprintf("Too many nested queries defined (exceeds %d).", settings.API.DataObjectNestedQueriesMax))
 		}
 	} else {
-		if nestCount > 0 {
+		if st.nested > 0 {
 			return newQueryError("Double nested queries are not allowed.")
 		}
 	}
 
+	if st.clauses > settings.API.DataObjectQueryClausesMax {
+		return newQueryError(fmt.Sprintf("Too many lookups defined (exceeds %d).", settings.API.DataObjectQueryClausesMax))
+	}
+
+	return nil
+}
+
+func (doq *DataObjectQuery) validateMap(m map[string]interface{}, depth int, st *queryStats) error {
+	for name, props := range m {
+		if _, ok := logicalOperators[name]; ok {
+			if depth >= settings.API.DataObjectQueryDepthMax {
+				return newQueryError(fmt.Sprintf("Too deeply nested logical operators (exceeds %d).", settings.API.DataObjectQueryDepthMax))
+			}
+
+			lst, ok := props.([]interface{})
+			if !ok || len(lst) == 0 || len(lst) > maxListLength {
+				return newQueryError(fmt.Sprintf(`Expected non-empty list of dicts at "%s".`, name))
+			}
+
+			for _, item := range lst {
+				sub, ok := item.(map[string]interface{})
+				if !ok || len(sub) == 0 {
+					return newQueryError(fmt.Sprintf(`Expected non-empty list of dicts at "%s".`, name))
+				}
+
+				if err := doq.validateMap(sub, depth+1, st); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		propMap, ok := props.(map[string]interface{})
+		if !ok {
+			return newQueryError(fmt.Sprintf(`Expected dict at "%s".`, name))
+		}
+
+		if _, ok = propMap["_is"]; ok {
+			st.nested++
+		}
+
+		st.clauses += len(propMap)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/go-pg/pg/v9/orm"
+	json "github.com/json-iterator/go"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func testFilterFields(fields ...*models.DataObjectField) map[string]models.FilterField {
+	ret := make(map[string]models.FilterField, len(fields))
+
+	for _, f := range fields {
+		f.TableAlias = "t"
+		f.Mapping = "_" + f.FName
+		ret[f.FName] = f
+	}
+
+	return ret
+}
+
+func testQueryMap(s string) map[string]interface{} {
+	var m map[string]interface{}
+
+	So(json.Unmarshal([]byte(s), &m), ShouldBeNil)
+
+	return m
+}
+
+// testQuerySQL returns SQL of query parsed from JSON string.
+func testQuerySQL(doq *DataObjectQuery, s string) (string, error) {
+	m := testQueryMap(s)
+	if err := doq.Validate(m, true); err != nil {
+		return "", err
+	}
+
+	q, err := doq.ParseMap(nil, nil, orm.NewQuery(nil), m)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := q.AppendQuery(orm.NewFormatter(), nil)
+
+	return string(b), err
+}
+
+func TestDataObjectQueryValidate(t *testing.T) {
+	Convey("Given data object query validation", t, func() {
+		doq := NewDataObjectQuery(testFilterFields(&models.DataObjectField{FName: "a", FType: models.FieldIntegerType}))
+
+		Convey("logical operator requires non-empty list of dicts", func() {
+			So(doq.Validate(testQueryMap(`{"_or": []}`), true), ShouldNotBeNil)
+			So(doq.Validate(testQueryMap(`{"_or": {"a": {"_eq": 1}}}`), true), ShouldNotBeNil)
+			So(doq.Validate(testQueryMap(`{"_and": [{}]}`), true), ShouldNotBeNil)
+			So(doq.Validate(testQueryMap(`{"_not": [{"a": {"_eq": 1}}]}`), true), ShouldBeNil)
+		})
+		Convey("nesting depth of logical operators is limited", func() {
+			So(doq.Validate(testQueryMap(`{"_or": [{"_and": [{"_not": [{"a": {"_eq": 1}}]}]}]}`), true), ShouldBeNil)
+			So(doq.Validate(testQueryMap(`{"_or": [{"_and": [{"_not": [{"_or": [{"a": {"_eq": 1}}]}]}]}]}`), true), ShouldNotBeNil)
+		})
+		Convey("lookups in logical operators count towards clauses limit", func() {
+			lst := make([]interface{}, 0, maxListLength)
+			for i := 0; i < maxListLength; i++ {
+				lst = append(lst, map[string]interface{}{"a": map[string]interface{}{"_gt": 1.0, "_lt": 5.0, "_neq": 3.0}})
+			}
+
+			So(doq.Validate(map[string]interface{}{"_or": lst}, true), ShouldNotBeNil)
+		})
+		Convey("field lookups must be a dict", func() {
+			So(doq.Validate(testQueryMap(`{"a": 1}`), true), ShouldNotBeNil)
+		})
+	})
+}
+
+func TestDataObjectQueryParse(t *testing.T) {
+	Convey("Given data object query", t, func() {
+		doq := NewDataObjectQuery(testFilterFields(
+			&models.DataObjectField{FName: "a", FType: models.FieldIntegerType},
+			&models.DataObjectField{FName: "b", FType: models.FieldStringType},
+		))
+
+		Convey("_or joins nested queries with OR", func() {
+			sql, err := testQuerySQL(doq, `{"_or": [{"a": {"_eq": 1}}, {"b": {"_eq": "x"}}]}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_a')::integer = 1`)
+			So(sql, ShouldContainSubstring, " OR ")
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_b')::varchar(128) = 'x'`)
+		})
+		Convey("_and joins nested queries with AND", func() {
+			sql, err := testQuerySQL(doq, `{"_and": [{"a": {"_gt": 1}}, {"a": {"_lt": 5}}]}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_a')::integer > 1`)
+			So(sql, ShouldContainSubstring, " AND ")
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_a')::integer < 5`)
+			So(sql, ShouldNotContainSubstring, " OR ")
+		})
+		Convey("_not negates nested queries", func() {
+			sql, err := testQuerySQL(doq, `{"_not": [{"a": {"_eq": 1}}]}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, "TRUE")
+			So(sql, ShouldContainSubstring, " NOT ")
+			So(sql, ShouldContainSubstring, `("t"."_data"->'_a')::integer = 1`)
+		})
+		Convey("invalid field in nested query results in error", func() {
+			_, err := testQuerySQL(doq, `{"_or": [{"c": {"_eq": 1}}]}`)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("invalid lookup in nested query results in error", func() {
+			_, err := testQuerySQL(doq, `{"_and": [{"a": {"_unknown": 1}}]}`)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
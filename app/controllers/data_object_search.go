@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+const (
+	rankOrderField  = "_rank"
+	searchMaxLength = 256
+)
+
+// searchLanguage returns text search configuration of field. Returned value is always one of models.SearchLanguages.
+func searchLanguage(f models.FilterField) string {
+	if dof, ok := f.(*models.DataObjectField); ok {
+		return dof.SearchLanguage()
+	}
+
+	return models.DefaultSearchLanguage
+}
+
+// searchVectorSQL returns tsvector expression of field. Language is inlined so that it matches expression indexes.
+func searchVectorSQL(f models.FilterField) string {
+	return fmt.Sprintf("to_tsvector('%s', %s)", searchLanguage(f), f.SQLName())
+}
+
+// searchRankSQL returns rank expression of search text for field.
+// Order fields cannot carry query params so search text is inlined hex encoded.
+func searchRankSQL(f models.FilterField, text string) string {
+	return fmt.Sprintf("ts_rank(%s, websearch_to_tsquery('%s', convert_from(decode('%s', 'hex'), 'UTF8')))",
+		searchVectorSQL(f), searchLanguage(f), hex.EncodeToString([]byte(text)))
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestSearchLookup(t *testing.T) {
+	Convey("Given text field with search language", t, func() {
+		fields := testFilterFields(
+			&models.DataObjectField{FName: "a", FType: models.FieldTextType, Language: "english"},
+			&models.DataObjectField{FName: "b", FType: models.FieldStringType, Language: "klingon"},
+			&models.DataObjectField{FName: "c", FType: models.FieldIntegerType},
+		)
+		doq := NewDataObjectQuery(fields)
+
+		Convey("unknown language falls back to default one", func() {
+			So(searchLanguage(fields["a"]), ShouldEqual, "english")
+			So(searchLanguage(fields["b"]), ShouldEqual, models.DefaultSearchLanguage)
+		})
+		Convey("_search matches tsvector of field", func() {
+			sql, err := testQuerySQL(doq, `{"a": {"_search": " fat cats "}}`)
+			So(err, ShouldBeNil)
+			So(sql, ShouldContainSubstring, `to_tsvector('english', ("t"."_data"->'_a')::text) @@ websearch_to_tsquery('english', 'fat cats')`)
+		})
+		Convey("_search tracks rank with hex encoded text", func() {
+			_, err := testQuerySQL(doq, `{"a": {"_search": "it's"}}`)
+			So(err, ShouldBeNil)
+			So(doq.ranks, ShouldHaveLength, 1)
+			So(doq.ranks[0], ShouldContainSubstring, "decode('69742773', 'hex')")
+			So(doq.ranks[0], ShouldNotContainSubstring, "it's")
+		})
+		Convey("_search rejects empty and too long text", func() {
+			_, err := testQuerySQL(doq, `{"a": {"_search": "  "}}`)
+			So(err, ShouldNotBeNil)
+			_, err = testQuerySQL(doq, `{"a": {"_search": "`+strings.Repeat("a", searchMaxLength+1)+`"}}`)
+			So(err, ShouldNotBeNil)
+		})
+		Convey("_search rank is tracked at top level and in _and", func() {
+			_, err := testQuerySQL(doq, `{"_and": [{"a": {"_search": "cats"}}]}`)
+			So(err, ShouldBeNil)
+			So(doq.ranks, ShouldHaveLength, 1)
+			So(doq.OrderFields(&models.Class{ID: 1, Name: "cls"}), ShouldContainKey, rankOrderField)
+		})
+		Convey("_search rank is not tracked in _or and _not", func() {
+			_, err := testQuerySQL(doq, `{"_or": [{"a": {"_search": "cats"}}, {"c": {"_eq": 1}}], "_not": [{"a": {"_search": "dogs"}}]}`)
+			So(err, ShouldBeNil)
+			So(doq.ranks, ShouldBeEmpty)
+			So(doq.untracked, ShouldEqual, 0)
+			So(doq.OrderFields(&models.Class{ID: 1, Name: "cls"}), ShouldNotContainKey, rankOrderField)
+		})
+		Convey("_search rank is not tracked in _and nested in _not", func() {
+			_, err := testQuerySQL(doq, `{"_not": [{"_and": [{"a": {"_search": "cats"}}]}]}`)
+			So(err, ShouldBeNil)
+			So(doq.ranks, ShouldBeEmpty)
+		})
+		Convey("ordering by _rank without tracked _search results in error", func() {
+			p := &PaginatorOrderedDB{OrderFields: doq.OrderFields(&models.Class{ID: 1, Name: "cls"})}
+			cur := testKeysetCursor(p, url.Values{"order_by": {rankOrderField}})
+			So(cur.err, ShouldResemble, missingOrderFieldError(rankOrderField))
+			So(cur.err.(*api.Error).Code, ShouldEqual, http.StatusBadRequest)
+		})
+		Convey("_search is not supported for non text fields", func() {
+			_, err := testQuerySQL(doq, `{"c": {"_search": "a"}}`)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestComputedOrderField(t *testing.T) {
+	Convey("Given computed order field", t, func() {
+		Convey("of data object class, last value is computed for object", func() {
+			f := newComputedOrderField(&models.Class{ID: 5, Name: "cls"}, "rank")
+			So(f.SQLName(), ShouldEqual, "rank")
+			So(f.LastValueSQL(), ShouldEqual, `(SELECT rank FROM ?schema.data_dataobject AS "data_object" WHERE "data_object"."id" = ?)`)
+		})
+		Convey("of user class, last value is computed for profile of user class", func() {
+			f := newComputedOrderField(&models.Class{ID: 5, Name: models.UserClassName}, "rank")
+			So(f.LastValueSQL(), ShouldEqual,
+				`(SELECT rank FROM ?schema.data_dataobject AS "profile" WHERE "profile"."_klass_id" = 5 AND "profile"."owner_id" = ?)`)
+		})
+	})
+}
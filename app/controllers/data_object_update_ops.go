@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"math"
+	"reflect"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+var updateOps = map[string]*updateOp{}
+
+func registerUpdateOp(op *updateOp, names ...string) {
+	for _, name := range names {
+		updateOps[name] = op
+	}
+}
+
+// updateOp represents atomic field update operator that is applied on current value of locked object.
+type updateOp struct {
+	supportedTypes []string
+	apply          func(f *models.DataObjectField, op string, cur, arg interface{}) (interface{}, error)
+}
+
+func (op *updateOp) Supports(f *models.DataObjectField) bool {
+	for _, t := range op.supportedTypes {
+		if t == f.FType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dataObjectFieldUpdate converts payload value to field's internal type.
+// If value is an update operator object (e.g. {"_increment": 1}), operator is applied on current field value.
+func dataObjectFieldUpdate(f *models.DataObjectField, o *models.DataObject, val interface{}) (interface{}, error) {
+	m, ok := val.(map[string]interface{})
+	if !ok || len(m) != 1 || f.FType == models.FieldObjectType {
+		return dataObjectFieldValue(f, val)
+	}
+
+	for name, arg := range m {
+		op, ok := updateOps[name]
+		if !ok {
+			break
+		}
+
+		if !op.Supports(f) {
+			return nil, newFieldError(`Operator "%s" is not supported for field of type "%s".`, name, f.FType)
+		}
+
+		return op.apply(f, name, f.Get(o), arg)
+	}
+
+	return dataObjectFieldValue(f, val)
+}
+
+// containsValue returns index of value in list or -1 if it is not found.
+func containsValue(lst []interface{}, v interface{}) int {
+	for i, item := range lst {
+		if reflect.DeepEqual(item, v) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func applyListOp(op string, cur, arg []interface{}) []interface{} {
+	ret := append(make([]interface{}, 0, len(cur)+len(arg)), cur...)
+
+	for _, v := range arg {
+		switch op {
+		case "_add":
+			ret = append(ret, v)
+
+		case "_addunique":
+			if containsValue(ret, v) == -1 {
+				ret = append(ret, v)
+			}
+
+		case "_remove":
+			filtered := ret[:0]
+
+			for _, item := range ret {
+				if !reflect.DeepEqual(item, v) {
+					filtered = append(filtered, item)
+				}
+			}
+
+			ret = filtered
+		}
+	}
+
+	return ret
+}
+
+func init() {
+	// Increment of numeric fields.
+	registerUpdateOp(&updateOp{
+		supportedTypes: []string{models.FieldIntegerType, models.FieldFloatType},
+		apply: func(f *models.DataObjectField, op string, cur, arg interface{}) (interface{}, error) {
+			if f.FType == models.FieldFloatType {
+				v, ok := toFloat(arg)
+				if !ok {
+					return nil, newFieldError("A valid number is required.")
+				}
+
+				if cur == nil {
+					return v, nil
+				}
+
+				return cur.(float64) + v, nil
+			}
+
+			v, ok := toInteger(arg)
+			if !ok {
+				return nil, newFieldError("A valid integer is required.")
+			}
+
+			if cur != nil {
+				v += cur.(int)
+			}
+
+			if v > math.MaxInt32 || v < math.MinInt32 {
+				return nil, newFieldError("Ensure this value is within integer range.")
+			}
+
+			return v, nil
+		}},
+		"_increment",
+	)
+
+	// List operators for arrays and relations.
+	registerUpdateOp(&updateOp{
+		supportedTypes: []string{models.FieldArrayType, models.FieldRelationType},
+		apply: func(f *models.DataObjectField, op string, cur, arg interface{}) (interface{}, error) {
+			if f.FType == models.FieldRelationType {
+				ids, err := toRelationValue(arg)
+				if err != nil {
+					return nil, err
+				}
+
+				var curList, argList []interface{}
+
+				if cur != nil {
+					for _, id := range cur.([]int) {
+						curList = append(curList, id)
+					}
+				}
+
+				for _, id := range ids.([]int) {
+					argList = append(argList, id)
+				}
+
+				return toRelationValue(applyListOp(op, curList, argList))
+			}
+
+			argList, ok := arg.([]interface{})
+			if !ok {
+				return nil, errFieldInvalidList
+			}
+
+			for _, v := range argList {
+				switch v.(type) {
+				case string, float64, bool:
+				default:
+					return nil, newFieldError("List can only contain strings, numbers and booleans.")
+				}
+			}
+
+			var curList []interface{}
+
+			if cur != nil {
+				curList, _ = cur.([]interface{})
+			}
+
+			return dataObjectFieldValue(f, applyListOp(op, curList, argList))
+		}},
+		"_add", "_addunique", "_remove",
+	)
+}
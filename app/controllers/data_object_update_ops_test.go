@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestDataObjectFieldUpdate(t *testing.T) {
+	Convey("Given data object with current values", t, func() {
+		o := &models.DataObject{}
+		initDataObjectHstores(o)
+
+		field := func(typ string, cur interface{}) *models.DataObjectField {
+			f := &models.DataObjectField{FName: typ, FType: typ, Mapping: "_" + typ}
+			if cur != nil {
+				So(f.Set(o.Data, cur), ShouldBeNil)
+			}
+
+			return f
+		}
+
+		Convey("_increment adds to integer value", func() {
+			f := field(models.FieldIntegerType, 5)
+			v, err := dataObjectFieldUpdate(f, o, map[string]interface{}{"_increment": -2.0})
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 3)
+		})
+		Convey("_increment of missing value starts from zero", func() {
+			f := field(models.FieldFloatType, nil)
+			v, err := dataObjectFieldUpdate(f, o, map[string]interface{}{"_increment": 1.5})
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 1.5)
+		})
+		Convey("_increment checks integer range", func() {
+			f := field(models.FieldIntegerType, 2147483647)
+			_, err := dataObjectFieldUpdate(f, o, map[string]interface{}{"_increment": 1.0})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("_add, _addunique and _remove modify array", func() {
+			f := field(models.FieldArrayType, []interface{}{"a", "b"})
+
+			v, err := dataObjectFieldUpdate(f, o, map[string]interface{}{"_add": []interface{}{"a"}})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []interface{}{"a", "b", "a"})
+
+			v, err = dataObjectFieldUpdate(f, o, map[string]interface{}{"_addunique": []interface{}{"a", "c"}})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []interface{}{"a", "b", "c"})
+
+			v, err = dataObjectFieldUpdate(f, o, map[string]interface{}{"_remove": []interface{}{"a"}})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []interface{}{"b"})
+		})
+		Convey("array operators reject nested values", func() {
+			f := field(models.FieldArrayType, nil)
+			_, err := dataObjectFieldUpdate(f, o, map[string]interface{}{"_add": []interface{}{[]interface{}{}}})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("relation operators keep ids unique", func() {
+			f := field(models.FieldRelationType, []int{1, 2})
+
+			v, err := dataObjectFieldUpdate(f, o, map[string]interface{}{"_add": []interface{}{2.0, 3.0}})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []int{1, 2, 3})
+
+			v, err = dataObjectFieldUpdate(f, o, map[string]interface{}{"_remove": []interface{}{1.0}})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, []int{2})
+		})
+		Convey("operator is rejected for unsupported field type", func() {
+			f := field(models.FieldStringType, "a")
+			_, err := dataObjectFieldUpdate(f, o, map[string]interface{}{"_increment": 1.0})
+			So(err, ShouldNotBeNil)
+		})
+		Convey("object field treats operator-like keys as plain value", func() {
+			f := field(models.FieldObjectType, nil)
+			v, err := dataObjectFieldUpdate(f, o, map[string]interface{}{"_increment": 1.0})
+			So(err, ShouldBeNil)
+			So(v, ShouldResemble, map[string]interface{}{"_increment": 1.0})
+		})
+		Convey("plain values are converted as usual", func() {
+			f := field(models.FieldIntegerType, 5)
+			v, err := dataObjectFieldUpdate(f, o, 7.0)
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 7)
+		})
+	})
+}
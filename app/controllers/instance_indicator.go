@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"net/http"
+
 	"github.com/go-pg/pg/v9"
 	"github.com/go-pg/pg/v9/orm"
 	"github.com/labstack/echo/v4"
@@ -29,3 +31,26 @@ func (ctr *Controller) updateInstanceIndicatorValue(c echo.Context, db orm.DB, t
 
 	return mgr.Update(o)
 }
+
+// checkStorageLimit returns error if storage size of instance increased by size would exceed storage limit of its plan.
+func (ctr *Controller) checkStorageLimit(c echo.Context, size int64) error {
+	sub := c.Get(contextSubscriptionKey).(*models.Subscription)
+
+	limit := c.Get(contextAdminLimitKey).(*models.AdminLimit).StorageLimit(sub)
+	if limit < 0 {
+		return nil
+	}
+
+	instance := c.Get(settings.ContextInstanceKey).(*models.Instance)
+	o := &models.InstanceIndicator{InstanceID: instance.ID, Type: models.InstanceIndicatorTypeStorageSize}
+
+	if err := ctr.q.NewInstanceIndicatorManager(c).ByInstanceAndTypeQ(o).Select(); err != nil && err != pg.ErrNoRows {
+		return err
+	}
+
+	if int64(o.Value)+size > int64(limit) {
+		return api.NewGenericError(http.StatusForbidden, "Storage limit exceeded.")
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
 	"strconv"
 	"strings"
@@ -28,6 +29,9 @@ var (
 	}
 	errorType        = reflect.TypeOf((*error)(nil)).Elem()
 	errStopIteration = errors.New("stop iteration")
+
+	// paginationPreservedParams are query params carried over to next and prev urls.
+	paginationPreservedParams = []string{"query", fieldsQuery, excludedFieldsQuery, expandQuery}
 )
 
 type cursorObject struct {
@@ -44,6 +48,7 @@ type cursor struct {
 	extractor func(interface{}) interface{}
 	first     interface{}
 	last      interface{}
+	preserved url.Values
 }
 
 func newCursor(c echo.Context, defaultOrderAsc bool) *cursor {
@@ -77,29 +82,49 @@ func newCursor(c echo.Context, defaultOrderAsc bool) *cursor {
 	}
 
 	return &cursor{limit: limit, forward: forward, lastPk: lastPk,
-		defaultOrderAsc: defaultOrderAsc, orderAsc: orderAsc, extractor: extractor}
+		defaultOrderAsc: defaultOrderAsc, orderAsc: orderAsc, extractor: extractor,
+		preserved: preservedPaginationParams(c)}
 }
 
-func (c *cursor) buildURL(path string, direction int, o interface{}) string {
-	lastPk := c.lastPk
-	if o != nil {
-		lastPk = o.(cursorObject).id
+// params returns pagination params common for all cursors of page in specified direction.
+func (c *cursor) params(direction, lastPk int) url.Values {
+	params := url.Values{}
+
+	for k, v := range c.preserved {
+		params[k] = v
 	}
 
-	base := fmt.Sprintf("%s?direction=%d", path, direction)
+	params.Set("direction", strconv.Itoa(direction))
+
 	if c.limit != defaultLimit {
-		base += fmt.Sprintf("&page_size=%d", c.limit)
+		params.Set("page_size", strconv.Itoa(c.limit))
 	}
 
 	if lastPk != 0 {
-		base += fmt.Sprintf("&last_pk=%d", lastPk)
+		params.Set("last_pk", strconv.Itoa(lastPk))
 	}
 
+	return params
+}
+
+func (c *cursor) buildURL(path string, direction int, o interface{}) string {
+	lastPk := c.lastPk
+	if o != nil {
+		lastPk = o.(cursorObject).id
+	}
+
+	params := c.params(direction, lastPk)
+
 	if c.orderAsc != c.defaultOrderAsc {
-		base += fmt.Sprintf("&ordering=%s", orderingMap[c.orderAsc])
+		params.Set("ordering", orderingMap[c.orderAsc])
 	}
 
-	return base
+	return cursorURL(path, params)
+}
+
+// cursorURL returns url of page with pagination params encoded as signed cursor.
+func cursorURL(path string, params url.Values) string {
+	return fmt.Sprintf("%s?%s=%s", path, api.CursorQuery, api.EncodeCursor(path, params))
 }
 
 func (c *cursor) Limit() int {
@@ -164,37 +189,51 @@ func (p *PaginatorDB) FilterObjects(cursor Cursorer) error {
 }
 
 func (p *PaginatorDB) ProcessObjects(c echo.Context, cursor Cursorer, typ reflect.Type, serializer serializers.Serializer, responseLimit *int) ([]api.RawMessage, error) {
-	var ret []api.RawMessage
+	var (
+		ret  []api.RawMessage
+		objs []interface{}
+		last interface{}
+		e    error
+	)
 
 	q := p.Query
 
-	// Create foreach function using reflection.
-	var (
-		resp      []byte
-		e         error
-		obj, last interface{}
-	)
+	// Serializers that preload data need whole batch of objects before serializing any of them.
+	preparer, prepare := serializer.(serializers.Preparer)
+	prepare = prepare && preparer.NeedsPrepare()
+
+	process := func(obj interface{}) error {
+		if *responseLimit <= 0 {
+			return errStopIteration
+		}
 
+		resp, err := api.MarshalRaw(serializer.Response(obj))
+		if err != nil {
+			return err
+		}
+
+		if last == nil {
+			cursor.SetFirst(obj)
+		}
+		last = obj
+
+		ret = append(ret, resp)
+		*responseLimit -= len(resp)
+
+		return nil
+	}
+
+	// Create foreach function using reflection.
 	foreach := reflect.MakeFunc(reflect.FuncOf([]reflect.Type{typ}, []reflect.Type{errorType}, false),
 		func(args []reflect.Value) (results []reflect.Value) {
-			if *responseLimit <= 0 {
-				return []reflect.Value{reflect.ValueOf(&errStopIteration).Elem()}
-			}
+			o := args[0].Interface()
 
-			obj = args[0].Interface()
-			resp, e = api.Marshal(c, serializer.Response(obj))
-			if e != nil {
+			if prepare {
+				objs = append(objs, o)
+			} else if e = process(o); e != nil {
 				return []reflect.Value{reflect.ValueOf(&e).Elem()}
 			}
 
-			if last == nil {
-				cursor.SetFirst(obj)
-			}
-			last = obj
-
-			ret = append(ret, resp)
-			*responseLimit -= len(resp)
-
 			return []reflect.Value{reflect.Zero(errorType)}
 		})
 
@@ -202,6 +241,22 @@ func (p *PaginatorDB) ProcessObjects(c echo.Context, cursor Cursorer, typ reflec
 		return nil, err
 	}
 
+	if prepare {
+		if err := preparer.Prepare(objs); err != nil {
+			return nil, err
+		}
+
+		for _, o := range objs {
+			if err := process(o); err != nil {
+				if err == errStopIteration {
+					break
+				}
+
+				return nil, err
+			}
+		}
+	}
+
 	if last != nil {
 		cursor.SetLast(last)
 	}
@@ -272,3 +327,16 @@ func Paginate(c echo.Context, cursor Cursorer, typ interface{}, serializer seria
 
 	return ret, nil
 }
+
+// preservedPaginationParams returns query params that should be kept by next and prev urls.
+func preservedPaginationParams(c echo.Context) url.Values {
+	params := url.Values{}
+
+	for _, param := range paginationPreservedParams {
+		if v := c.QueryParam(param); v != "" {
+			params.Set(param, v)
+		}
+	}
+
+	return params
+}
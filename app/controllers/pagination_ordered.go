@@ -2,53 +2,143 @@ package controllers
 
 import (
 	"fmt"
-	"net/url"
 	"reflect"
+	"strings"
+	"time"
 
+	json "github.com/json-iterator/go"
 	"github.com/labstack/echo/v4"
 
 	"github.com/Syncano/orion/app/api"
 	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+	"github.com/Syncano/pkg-go/v2/database/fields"
 )
 
-const orderByQuery = "order_by"
+const (
+	orderByQuery     = "order_by"
+	lastValueQuery   = "last_value"
+	orderBySeparator = ","
+)
+
+var nullsOrderingMap = map[bool]string{
+	true:  "NULLS LAST",
+	false: "NULLS FIRST",
+}
 
 type keysetcursorObject struct {
-	id  int
-	val interface{}
+	id   int
+	vals []interface{}
+}
+
+// keysetOrder is a single column of keyset ordering.
+type keysetOrder struct {
+	field models.OrderField
+	asc   bool
 }
 
 type keysetcursor struct {
 	*cursor
-	field     models.OrderField
-	orderBy   string
-	lastValue interface{}
+	orders     []*keysetOrder
+	idAsc      bool
+	orderBy    string
+	lastValues []interface{}
+	err        error
 }
 
 func (c *keysetcursor) buildURL(path string, direction int, o interface{}) string {
 	lastPk := c.lastPk
-	lastVal := c.lastValue
+	lastVals := c.lastValues
 
 	if o != nil {
 		obj := o.(keysetcursorObject)
 		lastPk = obj.id
-		lastVal = obj.val
+		lastVals = obj.vals
 	}
 
-	base := fmt.Sprintf("%s?direction=%d&order_by=%s", path, direction, c.orderBy)
-	if c.limit != defaultLimit {
-		base += fmt.Sprintf("&page_size=%d", c.limit)
+	params := c.params(direction, lastPk)
+	params.Set(orderByQuery, c.orderBy)
+
+	if v, ok := c.encodeLastValues(lastVals); ok {
+		params.Set(lastValueQuery, v)
 	}
 
-	if lastPk != 0 {
-		base += fmt.Sprintf("&last_pk=%d", lastPk)
+	return cursorURL(path, params)
+}
+
+// encodeLastValues returns last values of order fields as a string. Single field ordering uses plain value
+// (omitted if null) so that it stays compatible with legacy urls, otherwise values are encoded as JSON array.
+func (c *keysetcursor) encodeLastValues(vals []interface{}) (string, bool) {
+	if len(vals) != len(c.orders) {
+		return "", false
 	}
 
-	if v, err := c.field.ToString(lastVal); err == nil {
-		base += fmt.Sprintf("&last_value=%s", url.QueryEscape(v))
+	if len(c.orders) == 1 {
+		v, err := c.orders[0].field.ToString(vals[0])
+		return v, err == nil
 	}
 
-	return base
+	strs := make([]*string, len(vals))
+
+	for i, val := range vals {
+		if v, err := c.orders[i].field.ToString(val); err == nil {
+			strs[i] = &v
+		}
+	}
+
+	b, err := json.Marshal(strs)
+
+	return string(b), err == nil
+}
+
+// decodeLastValues parses last values of order fields encoded by encodeLastValues.
+func (c *keysetcursor) decodeLastValues(s string) error {
+	c.lastValues = make([]interface{}, len(c.orders))
+
+	if s == "" {
+		return nil
+	}
+
+	if len(c.orders) == 1 {
+		c.lastValues[0], _ = lastValueFromString(c.orders[0].field, s)
+		return nil
+	}
+
+	var strs []*string
+
+	if err := json.Unmarshal([]byte(s), &strs); err != nil || len(strs) != len(c.orders) {
+		return api.NewBadRequestError(fmt.Sprintf(`Invalid "%s".`, lastValueQuery))
+	}
+
+	for i, v := range strs {
+		if v != nil {
+			c.lastValues[i], _ = lastValueFromString(c.orders[i].field, *v)
+		}
+	}
+
+	return nil
+}
+
+// lastValueFromString parses last value of order field. Datetimes are accepted in RFC3339 format as well,
+// as it is what clients get in responses and legacy urls could contain it.
+func lastValueFromString(f models.OrderField, s string) (interface{}, error) {
+	v, err := f.FromString(s)
+
+	if ff, ok := f.(models.FilterField); ok && err != nil && ff.Type() == models.FieldDatetimeType {
+		var t time.Time
+
+		if t, err = time.Parse(time.RFC3339Nano, s); err == nil {
+			return fields.NewTime(&t), nil
+		}
+	}
+
+	return v, err
+}
+
+// isAsc returns true if column of specified order is scanned in ascending order.
+// Order of all columns is reversed if direction is not forward.
+func (c *keysetcursor) isAsc(asc bool) bool {
+	return asc == c.forward
 }
 
 func (c *keysetcursor) NextURL(path string) string {
@@ -69,6 +159,128 @@ func (c *keysetcursor) PrevURL(path string) string {
 	return c.buildURL(path, 0, o)
 }
 
+// computedOrderField is an order field with value computed by query (e.g. search rank or distance).
+// Its value is not a part of object so keyset pagination recomputes it for last_pk instead.
+type computedOrderField struct {
+	expr   string
+	source string
+}
+
+func newComputedOrderField(class *models.Class, expr string) *computedOrderField {
+	source := `FROM ?schema.data_dataobject AS "data_object" WHERE "data_object"."id" = ?`
+	if class.Name == models.UserClassName {
+		source = fmt.Sprintf(`FROM ?schema.data_dataobject AS "profile" WHERE "profile"."_klass_id" = %d AND "profile"."owner_id" = ?`,
+			class.ID)
+	}
+
+	return &computedOrderField{expr: expr, source: source}
+}
+
+func (f *computedOrderField) SQLName() string {
+	return f.expr
+}
+
+func (f *computedOrderField) Get(o interface{}) interface{} {
+	return nil
+}
+
+func (f *computedOrderField) ToString(v interface{}) (string, error) {
+	return "", models.ErrNilValue
+}
+
+func (f *computedOrderField) FromString(s string) (interface{}, error) {
+	return nil, models.ErrNilValue
+}
+
+// LastValueSQL returns subquery of field value for object with pk passed as param.
+func (f *computedOrderField) LastValueSQL() string {
+	return fmt.Sprintf("(SELECT %s %s)", f.expr, f.source)
+}
+
+// keysetCond is SQL condition along with its params.
+type keysetCond struct {
+	sql    string
+	params []interface{}
+}
+
+// keysetColumnConds returns conditions matching objects with column value equal to last value and
+// objects with column value that comes after it in scan order. Nulls come last in requested order,
+// so first in reversed one. Nil after condition means that no value comes after last one.
+func (c *keysetcursor) keysetColumnConds(i int) (eq, after *keysetCond) {
+	o := c.orders[i]
+	sqlName := o.field.SQLName()
+	nullsLast := c.forward
+
+	sqlOp := "<"
+	if c.isAsc(o.asc) {
+		sqlOp = ">"
+	}
+
+	// Computed value is never null and is not stored, so compare with value recomputed for last object.
+	if cf, ok := o.field.(*computedOrderField); ok {
+		return &keysetCond{sql: fmt.Sprintf("%s = %s", sqlName, cf.LastValueSQL()), params: []interface{}{c.lastPk}},
+			&keysetCond{sql: fmt.Sprintf("%s %s %s", sqlName, sqlOp, cf.LastValueSQL()), params: []interface{}{c.lastPk}}
+	}
+
+	val := c.lastValues[i]
+
+	if val == nil {
+		eq = &keysetCond{sql: fmt.Sprintf("%s IS NULL", sqlName)}
+
+		if !nullsLast {
+			after = &keysetCond{sql: fmt.Sprintf("%s IS NOT NULL", sqlName)}
+		}
+
+		return eq, after
+	}
+
+	eq = &keysetCond{sql: fmt.Sprintf("%s = ?", sqlName), params: []interface{}{val}}
+	after = &keysetCond{sql: fmt.Sprintf("%s %s ?", sqlName, sqlOp), params: []interface{}{val}}
+
+	if nullsLast {
+		after.sql = fmt.Sprintf("(%s OR %s IS NULL)", after.sql, sqlName)
+	}
+
+	return eq, after
+}
+
+// afterCond returns condition matching objects that come after last object in scan order.
+// For columns c1..cn and pk it is a tuple comparison expanded so that every column can have its own order:
+// (c1 after v1) OR (c1 = v1 AND c2 after v2) OR ... OR (c1 = v1 AND ... AND cn = vn AND pk after last_pk)
+func (c *keysetcursor) afterCond() *keysetCond {
+	var (
+		ors      []string
+		params   []interface{}
+		eqs      []string
+		eqParams []interface{}
+	)
+
+	addOr := func(after *keysetCond) {
+		ors = append(ors, "("+strings.Join(append(eqs[:len(eqs):len(eqs)], after.sql), " AND ")+")")
+		params = append(append(params, eqParams...), after.params...)
+	}
+
+	for i := range c.orders {
+		eq, after := c.keysetColumnConds(i)
+
+		if after != nil {
+			addOr(after)
+		}
+
+		eqs = append(eqs, eq.sql)
+		eqParams = append(eqParams, eq.params...)
+	}
+
+	sqlOp := "<"
+	if c.isAsc(c.idAsc) {
+		sqlOp = ">"
+	}
+
+	addOr(&keysetCond{sql: fmt.Sprintf("?TableAlias.id %s ?", sqlOp), params: []interface{}{c.lastPk}})
+
+	return &keysetCond{sql: strings.Join(ors, " OR "), params: params}
+}
+
 type PaginatorOrderedDB struct {
 	*PaginatorDB
 	OrderFields map[string]models.OrderField
@@ -76,91 +288,115 @@ type PaginatorOrderedDB struct {
 
 func (p *PaginatorOrderedDB) FilterObjects(cursor Cursorer) error {
 	q := p.Query
-	isOrderAsc := cursor.IsOrderAsc()
-	limit := cursor.Limit()
-
 	cur := cursor.(*keysetcursor)
 
-	if cur.field == nil {
-		return api.NewBadRequestError(`Missing or unindexed field used as "order_by".`)
+	if cur.err != nil {
+		return cur.err
 	}
 
-	sqlName := cur.field.SQLName()
-
 	if cur.lastPk > 0 {
-		if cur.forward {
-			if cur.lastValue == nil {
-				// Nulls Last, so if we got null value, find more nulls that have higher pk.
-				// field IS NULL AND pk > last_pk
-				q = q.Where(fmt.Sprintf("%s IS NULL AND ?TableAlias.id > ?", sqlName),
-					cur.lastPk)
-			} else {
-				// Null value not yet reached, look for null values or with higher pair.
-				// field IS NULL OR (field, pk) > (last_val, last_pk)
-				q = q.Where(fmt.Sprintf("%s IS NULL OR (%s, ?TableAlias.id) > (?, ?)", sqlName, sqlName),
-					cur.lastValue, cur.lastPk)
-			}
-		} else {
-			if cur.lastValue == nil {
-				// Null value reached, look for values that are not null or other nulls with lower pk.
-				// field IS NOT NULL OR (field IS NULL AND pk < last_pk)
-				q = q.Where(fmt.Sprintf("%s IS NOT NULL OR (%s IS NULL AND ?TableAlias.id < ?)", sqlName, sqlName),
-					cur.lastPk)
-			} else {
-				// Last value was not null, so proceed normally.
-				// (field, pk) < (last_val, last_pk)
-				q = q.Where(fmt.Sprintf("(%s, ?TableAlias.id) < (?, ?)", sqlName),
-					cur.lastValue, cur.lastPk)
-			}
-		}
+		cond := cur.afterCond()
+		q = q.Where(cond.sql, cond.params...)
+	}
+
+	orderExprs := make([]string, 0, len(cur.orders)+1)
+
+	for _, o := range cur.orders {
+		orderExprs = append(orderExprs, fmt.Sprintf("%s %s %s", o.field.SQLName(), orderingMap[cur.isAsc(o.asc)], nullsOrderingMap[cur.forward]))
 	}
 
-	order := orderingMap[isOrderAsc]
-	p.Query = q.OrderExpr(fmt.Sprintf("%s %s, ?TableAlias.id %s", sqlName, order, order)).Limit(limit)
+	orderExprs = append(orderExprs, "?TableAlias.id "+orderingMap[cur.isAsc(cur.idAsc)])
+	p.Query = q.OrderExpr(strings.Join(orderExprs, ", ")).Limit(cursor.Limit())
 
 	return nil
 }
 
+// CreateCursor creates cursor for comma separated list of order fields, each of them prefixed with "-"
+// for descending order. Objects with equal values are ordered by id, in order of first field unless
+// "id" is explicitly used as last field.
 func (p *PaginatorOrderedDB) CreateCursor(c echo.Context, defaultOrderAsc bool) Cursorer {
 	cur := &keysetcursor{cursor: newCursor(c, defaultOrderAsc)}
+	cur.orderBy = c.QueryParam(orderByQuery)
+	names := strings.Split(cur.orderBy, orderBySeparator)
+	seen := make(map[string]struct{}, len(names))
 
-	// Ignore default ordering.
-	orderAsc := true
-	orderBy := c.QueryParam(orderByQuery)
-	cur.orderBy = orderBy
+	for i, name := range names {
+		asc := true
+		name = strings.TrimSpace(name)
 
-	if len(orderBy) > 0 && orderBy[0] == '-' {
-		orderAsc = false
-		orderBy = orderBy[1:]
-	}
+		if len(name) > 0 && name[0] == '-' {
+			asc = false
+			name = name[1:]
+		}
+
+		if i == 0 {
+			cur.idAsc = asc
+		}
+
+		if name == "id" && i > 0 {
+			if i < len(names)-1 {
+				cur.err = api.NewBadRequestError(`Field "id" has to be the last one in "order_by".`)
+				return cur
+			}
+
+			cur.idAsc = asc
 
-	for name, f := range p.OrderFields {
-		if orderBy == name {
-			cur.field = f
 			break
 		}
-	}
 
-	if s := c.QueryParam("last_value"); s != "" && cur.field != nil {
-		cur.lastValue, _ = cur.field.FromString(s)
-	}
+		if _, ok := seen[name]; ok {
+			cur.err = api.NewBadRequestError(fmt.Sprintf(`Field "%s" is used more than once in "order_by".`, name))
+			return cur
+		}
 
-	// Process order. Reverse order if direction is not forward.
-	if !cur.forward {
-		orderAsc = !orderAsc
+		seen[name] = struct{}{}
+
+		f, ok := p.OrderFields[name]
+		if !ok {
+			cur.err = missingOrderFieldError(name)
+			return cur
+		}
+
+		if len(cur.orders) == settings.API.DataObjectOrderFieldsMax {
+			cur.err = api.NewBadRequestError(fmt.Sprintf(`Too many fields in "order_by" (exceeds %d).`, settings.API.DataObjectOrderFieldsMax))
+			return cur
+		}
+
+		cur.orders = append(cur.orders, &keysetOrder{field: f, asc: asc})
 	}
 
-	cur.orderAsc = orderAsc
+	cur.err = cur.decodeLastValues(c.QueryParam(lastValueQuery))
+	cur.orderAsc = cur.isAsc(cur.orders[0].asc)
 	cur.extractor = func(v interface{}) interface{} {
+		vals := make([]interface{}, len(cur.orders))
+
+		for i, o := range cur.orders {
+			vals[i] = o.field.Get(v)
+		}
+
 		return keysetcursorObject{
-			id:  int(reflect.ValueOf(v).Elem().FieldByName("ID").Int()),
-			val: cur.field.Get(v),
+			id:   int(reflect.ValueOf(v).Elem().FieldByName("ID").Int()),
+			vals: vals,
 		}
 	}
 
 	return cur
 }
 
+// missingOrderFieldError returns error of order field that is not available.
+func missingOrderFieldError(name string) *api.Error {
+	if name == rankOrderField {
+		return api.NewBadRequestError(`Ordering by "_rank" requires "_search" lookup outside of "_or" and "_not" in query.`)
+	}
+
+	if strings.HasPrefix(name, distanceOrderPrefix) {
+		return api.NewBadRequestError(fmt.Sprintf(`Ordering by "%s" requires "_within_radius" lookup of field outside of "_or" and "_not" in query.`,
+			name))
+	}
+
+	return api.NewBadRequestError(`Missing or unindexed field used as "order_by".`)
+}
+
 func isValidOrderedPagination(s string) bool {
 	// "id" and "-id" should be processed in standard pagination as they don't require a keyset.
 	return s != "" && s != "id" && s != "-id"
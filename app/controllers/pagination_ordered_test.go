@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/orion/app/settings"
+)
+
+func testKeysetCursor(p *PaginatorOrderedDB, params url.Values) *keysetcursor {
+	req := httptest.NewRequest(http.MethodGet, "/?"+params.Encode(), nil)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+
+	return p.CreateCursor(c, true).(*keysetcursor)
+}
+
+func TestKeysetCursor(t *testing.T) {
+	Convey("Given ordered paginator", t, func() {
+		a := &models.DataObjectField{FName: "a", FType: models.FieldIntegerType, TableAlias: "t", Mapping: "_a"}
+		b := &models.DataObjectField{FName: "b", FType: models.FieldStringType, TableAlias: "t", Mapping: "_b"}
+		p := &PaginatorOrderedDB{OrderFields: map[string]models.OrderField{"a": a, "b": b}}
+
+		Convey("order_by is parsed into columns with their order", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a, -b"}})
+			So(cur.err, ShouldBeNil)
+			So(cur.orders, ShouldHaveLength, 2)
+			So(cur.orders[0].field, ShouldEqual, a)
+			So(cur.orders[0].asc, ShouldBeTrue)
+			So(cur.orders[1].field, ShouldEqual, b)
+			So(cur.orders[1].asc, ShouldBeFalse)
+			So(cur.idAsc, ShouldBeTrue)
+			So(cur.IsOrderAsc(), ShouldBeTrue)
+		})
+		Convey("id as last column defines its order", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,-id"}})
+			So(cur.err, ShouldBeNil)
+			So(cur.orders, ShouldHaveLength, 1)
+			So(cur.idAsc, ShouldBeFalse)
+		})
+		Convey("fields after id result in error", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,id,b"}})
+			So(cur.err, ShouldNotBeNil)
+			So(cur.err.Error(), ShouldContainSubstring, `"id" has to be the last one`)
+		})
+		Convey("duplicated fields result in error", func() {
+			for _, orderBy := range []string{"a,a", "a,-a", "a,b,-a"} {
+				cur := testKeysetCursor(p, url.Values{"order_by": {orderBy}})
+				So(cur.err, ShouldNotBeNil)
+				So(cur.err.Error(), ShouldContainSubstring, `"a" is used more than once`)
+			}
+		})
+		Convey("fields above maximum result in error", func() {
+			p.OrderFields["c"] = &models.DataObjectField{FName: "c", FType: models.FieldIntegerType, TableAlias: "t", Mapping: "_c"}
+			p.OrderFields["d"] = &models.DataObjectField{FName: "d", FType: models.FieldIntegerType, TableAlias: "t", Mapping: "_d"}
+
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,b,c,id"}})
+			So(cur.err, ShouldBeNil)
+			So(cur.orders, ShouldHaveLength, settings.API.DataObjectOrderFieldsMax)
+
+			cur = testKeysetCursor(p, url.Values{"order_by": {"a,b,c,d"}})
+			So(cur.err, ShouldNotBeNil)
+			So(cur.err.Error(), ShouldContainSubstring, "Too many fields")
+		})
+		Convey("unknown field results in error", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,c"}})
+			So(cur.err, ShouldNotBeNil)
+			So(p.FilterObjects(cur), ShouldEqual, cur.err)
+		})
+		Convey("last values of multiple columns are decoded from JSON array", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,-b"}, "last_value": {`["1", null]`}})
+			So(cur.err, ShouldBeNil)
+			So(cur.lastValues, ShouldResemble, []interface{}{1, nil})
+		})
+		Convey("last values not matching columns result in error", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,-b"}, "last_value": {`["1"]`}})
+			So(cur.err, ShouldNotBeNil)
+			cur = testKeysetCursor(p, url.Values{"order_by": {"a,-b"}, "last_value": {`1`}})
+			So(cur.err, ShouldNotBeNil)
+		})
+		Convey("single column uses plain last value", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"-a"}, "last_value": {"5"}})
+			So(cur.err, ShouldBeNil)
+			So(cur.lastValues, ShouldResemble, []interface{}{5})
+
+			v, ok := cur.encodeLastValues([]interface{}{5})
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, "5")
+
+			_, ok = cur.encodeLastValues([]interface{}{nil})
+			So(ok, ShouldBeFalse)
+		})
+		Convey("last values round trip through encoding", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,-b"}})
+			vals := []interface{}{nil, "x"}
+
+			s, ok := cur.encodeLastValues(vals)
+			So(ok, ShouldBeTrue)
+			So(s, ShouldEqual, `[null,"x"]`)
+			So(cur.decodeLastValues(s), ShouldBeNil)
+			So(cur.lastValues, ShouldResemble, vals)
+		})
+		Convey("after condition compares columns in order followed by id", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,-b"}, "last_pk": {"10"}, "last_value": {`["1", "x"]`}})
+			cond := cur.afterCond()
+			sa, sb := a.SQLName(), b.SQLName()
+
+			So(cond.sql, ShouldEqual,
+				"(("+sa+" > ? OR "+sa+" IS NULL)) OR "+
+					"("+sa+" = ? AND ("+sb+" < ? OR "+sb+" IS NULL)) OR "+
+					"("+sa+" = ? AND "+sb+" = ? AND ?TableAlias.id > ?)")
+			So(cond.params, ShouldResemble, []interface{}{1, 1, "x", 1, "x", 10})
+		})
+		Convey("after condition of reversed direction puts nulls first", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a"}, "direction": {"0"}, "last_pk": {"10"}})
+			So(cur.IsOrderAsc(), ShouldBeFalse)
+
+			cond := cur.afterCond()
+			sa := a.SQLName()
+
+			So(cond.sql, ShouldEqual, "("+sa+" IS NOT NULL) OR ("+sa+" IS NULL AND ?TableAlias.id < ?)")
+			So(cond.params, ShouldResemble, []interface{}{10})
+		})
+		Convey("null last value in forward direction has nothing after it but ids", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a"}, "last_pk": {"10"}})
+			So(cur.afterCond().sql, ShouldEqual, "("+a.SQLName()+" IS NULL AND ?TableAlias.id > ?)")
+		})
+		Convey("next url of page keeps order and last values of last object", func() {
+			cur := testKeysetCursor(p, url.Values{"order_by": {"a,-b"}})
+			cur.last = keysetcursorObject{id: 7, vals: []interface{}{3, "y"}}
+
+			u, err := url.Parse(cur.NextURL("/objects/"))
+			So(err, ShouldBeNil)
+			So(u.Path, ShouldEqual, "/objects/")
+			So(u.Query().Get("cursor"), ShouldNotBeEmpty)
+		})
+	})
+}
+
+func TestComputedOrderFieldCond(t *testing.T) {
+	Convey("Given keyset cursor ordered by computed field", t, func() {
+		rank := newComputedOrderField(&models.Class{ID: 1, Name: "cls"}, "rank")
+		p := &PaginatorOrderedDB{OrderFields: map[string]models.OrderField{"rank": rank}}
+		cur := testKeysetCursor(p, url.Values{"order_by": {"-rank"}, "last_pk": {"10"}})
+
+		Convey("its value is recomputed for last object", func() {
+			cond := cur.afterCond()
+			So(cond.sql, ShouldEqual, "(rank < "+rank.LastValueSQL()+") OR (rank = "+rank.LastValueSQL()+" AND ?TableAlias.id < ?)")
+			So(cond.params, ShouldResemble, []interface{}{10, 10, 10})
+		})
+	})
+}
@@ -46,7 +46,7 @@ func (p *PaginatorRedis) ProcessObjects(c echo.Context, cursor Cursorer, typ ref
 
 	for i := 0; i < r.Len(); i++ {
 		obj = r.Index(i).Interface()
-		data, e = api.Marshal(c, serializer.Response(obj))
+		data, e = api.MarshalRaw(serializer.Response(obj))
 
 		if last == nil {
 			cursor.SetFirst(obj)
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/pkg-go/v2/database/fields"
+)
+
+func TestLastValueFromString(t *testing.T) {
+	Convey("Given datetime order field", t, func() {
+		f := &models.DataObjectField{FName: "d", FType: models.FieldDatetimeType}
+		expected := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+		Convey("value in its string format is parsed", func() {
+			s, err := f.ToString(fields.NewTime(&expected))
+			So(err, ShouldBeNil)
+
+			v, err := lastValueFromString(f, s)
+			So(err, ShouldBeNil)
+			So(v.(fields.Time).Time.Equal(expected), ShouldBeTrue)
+		})
+		Convey("value in RFC3339 format is parsed as well", func() {
+			v, err := lastValueFromString(f, expected.Format(time.RFC3339Nano))
+			So(err, ShouldBeNil)
+			So(v.(fields.Time).Time.Equal(expected), ShouldBeTrue)
+		})
+		Convey("RFC3339 is not accepted by field outside of cursor", func() {
+			_, err := f.FromString(expected.Format(time.RFC3339Nano))
+			So(err, ShouldNotBeNil)
+		})
+		Convey("invalid value results in error", func() {
+			_, err := lastValueFromString(f, "abc")
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
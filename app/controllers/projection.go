@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/Syncano/orion/app/api"
+	"github.com/Syncano/orion/app/serializers"
+)
+
+const (
+	fieldsQuery         = "fields"
+	excludedFieldsQuery = "excluded_fields"
+)
+
+// parseProjection returns projection defined by fields and excluded_fields query params.
+// Field names are validated against ones available in response. Returns nil if projection is not defined.
+func parseProjection(c echo.Context, available []string) (*serializers.Projection, error) {
+	fields := splitParam(c, fieldsQuery)
+	excluded := splitParam(c, excludedFieldsQuery)
+
+	if len(fields) == 0 && len(excluded) == 0 {
+		return nil, nil
+	}
+
+	availableMap := make(map[string]struct{}, len(available))
+	for _, name := range available {
+		availableMap[name] = struct{}{}
+	}
+
+	for param, names := range map[string][]string{fieldsQuery: fields, excludedFieldsQuery: excluded} {
+		for _, name := range names {
+			if _, ok := availableMap[name]; !ok {
+				return nil, api.NewError(http.StatusBadRequest,
+					map[string]interface{}{param: fmt.Sprintf(`Invalid field name specified: "%s".`, name)})
+			}
+		}
+	}
+
+	return serializers.NewProjection(fields, excluded), nil
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseProjection(t *testing.T) {
+	Convey("Given available response fields", t, func() {
+		available := []string{"id", "name", "text"}
+		newContext := func(query string) echo.Context {
+			req := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+			return echo.New().NewContext(req, httptest.NewRecorder())
+		}
+
+		Convey("no projection params returns nil projection", func() {
+			p, err := parseProjection(newContext(""), available)
+			So(err, ShouldBeNil)
+			So(p, ShouldBeNil)
+		})
+		Convey("fields and excluded_fields define projection", func() {
+			p, err := parseProjection(newContext("fields=id,name,text&excluded_fields=text"), available)
+			So(err, ShouldBeNil)
+			So(p.Includes("id"), ShouldBeTrue)
+			So(p.Includes("name"), ShouldBeTrue)
+			So(p.Includes("text"), ShouldBeFalse)
+		})
+		Convey("unknown field names are rejected", func() {
+			_, err := parseProjection(newContext("fields=id,unknown"), available)
+			So(err, ShouldNotBeNil)
+			_, err = parseProjection(newContext("excluded_fields=unknown"), available)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -55,7 +55,7 @@ func (ctr *Controller) launchTrigger(c echo.Context, db orm.DB, o interface{}, e
 		data = dtemp
 	}
 
-	ctr.db.AddDBCommitHook(db, func() error {
+	ctr.addDBCommitHook(c.Request().Context(), db, func() error {
 		return tasks.NewCeleryHandleTriggerEventTask(
 			instance.ID,
 			event, signal, data, map[string]interface{}{"changes": changes},
@@ -11,6 +11,7 @@ import (
 	"github.com/Syncano/orion/app/serializers"
 	"github.com/Syncano/orion/app/settings"
 	"github.com/Syncano/orion/app/validators"
+	"github.com/Syncano/pkg-go/v2/database/manager"
 )
 
 const (
@@ -84,20 +85,33 @@ func (ctr *Controller) UserList(c echo.Context) error {
 	// Prepare query.
 	q := mgr.Q(class, &o)
 
+	orderFields := class.OrderFields()
+
 	if _, e := c.QueryParams()["query"]; e {
 		var err error
-		q, err = NewDataObjectQuery(class.FilterFields()).Parse(ctr.q, c, q)
+
+		doq := NewDataObjectQuery(class.FilterFields())
+		q, err = doq.Parse(ctr.q, c, q)
 
 		if err != nil {
 			return err
 		}
+
+		for name, f := range doq.OrderFields(class) {
+			orderFields[name] = f
+		}
+
+		// Distances are scanned into joined profile.
+		if expr := doq.DistancesExpr(); expr != "" {
+			q = q.ColumnExpr(expr + ` AS "profile__distances"`)
+		}
 	}
 
 	// Prepare pagination.
 	var paginator Paginator
 
 	if isValidOrderedPagination(c.QueryParam(orderByQuery)) {
-		paginator = &PaginatorOrderedDB{PaginatorDB: &PaginatorDB{Query: q}, OrderFields: class.OrderFields()}
+		paginator = &PaginatorOrderedDB{PaginatorDB: &PaginatorDB{Query: q}, OrderFields: orderFields}
 	} else {
 		paginator = &PaginatorDB{Query: q}
 	}
@@ -107,12 +121,28 @@ func (ctr *Controller) UserList(c echo.Context) error {
 	// Return paginated results.
 	serializer := serializers.UserSerializer{Class: class}
 
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
+		return err
+	}
+
+	serializer.Projection = projection
+
+	if serializer.Expansion, err = ctr.newExpansion(c, class); err != nil {
+		return err
+	}
+
 	r, err := Paginate(c, cursor, (*models.User)(nil), serializer, paginator)
 	if err != nil {
 		return err
 	}
 
-	return api.Render(c, http.StatusOK, serializers.CreatePage(c, r, props))
+	return api.RenderWithWeakETag(c, http.StatusOK, serializers.CreatePage(c, r, props))
+}
+
+// userETag returns entity tag of user. Any change of user bumps revision of its profile.
+func userETag(o *models.User) string {
+	return api.ETag(o.Profile.Revision, o.Profile.UpdatedAt.Time)
 }
 
 func (ctr *Controller) UserRetrieve(c echo.Context) error {
@@ -122,8 +152,20 @@ func (ctr *Controller) UserRetrieve(c echo.Context) error {
 	}
 
 	class := c.Get(contextUserClassKey).(*models.Class)
+	serializer := serializers.UserSerializer{Class: class}
+
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
+		return err
+	}
 
-	if err := ctr.q.NewUserManager(c).ByIDQ(class, o).Select(); err != nil {
+	serializer.Projection = projection
+
+	if serializer.Expansion, err = ctr.newExpansion(c, class); err != nil {
+		return err
+	}
+
+	if err = ctr.q.NewUserManager(c).ByIDQ(class, o).Select(); err != nil {
 		if err == pg.ErrNoRows {
 			return api.NewNotFoundError(o)
 		}
@@ -131,14 +173,89 @@ func (ctr *Controller) UserRetrieve(c echo.Context) error {
 		return err
 	}
 
-	serializer := serializers.UserSerializer{Class: class}
+	if err = serializer.Prepare([]interface{}{o}); err != nil {
+		return err
+	}
 
-	return api.Render(c, http.StatusOK, serializer.Response(o))
+	return api.RenderWithETag(c, http.StatusOK, serializer.Response(o), userETag(o))
 }
 
 func (ctr *Controller) UserUpdate(c echo.Context) error {
-	// TODO: #15 Users updates/deletes
-	return api.NewPermissionDeniedError()
+	o := detailUserObject(c)
+	if o == nil {
+		return api.NewNotFoundError(o)
+	}
+
+	return ctr.userUpdate(c, o)
+}
+
+// userUpdate updates user profile fields. Update operators are applied on locked profile.
+func (ctr *Controller) userUpdate(c echo.Context, o *models.User) error {
+	p, err := parseDataObjectPayload(c)
+	if err != nil {
+		return err
+	}
+
+	expectedRevision, err := popExpectedRevision(p)
+	if err != nil {
+		return err
+	}
+
+	class := c.Get(contextUserClassKey).(*models.Class)
+	mgr := ctr.q.NewUserManager(c)
+	virt := dataObjectStateFields(class)
+
+	if err := mgr.RunInTransaction(func(tx *pg.Tx) error {
+		if err := manager.Lock(mgr.Query(o).Where("?TableAlias.id = ?", o.ID)); err != nil {
+			if err == pg.ErrNoRows {
+				return api.NewNotFoundError(o)
+			}
+
+			return err
+		}
+
+		profileMgr := ctr.q.NewDataObjectManager(c)
+		profileMgr.SetDB(tx)
+		o.Profile = &models.DataObject{}
+
+		if err := manager.Lock(profileMgr.ForProfileQ(class, o, o.Profile)); err != nil {
+			if err == pg.ErrNoRows {
+				return api.NewNotFoundError(o)
+			}
+
+			return err
+		}
+
+		if err := api.CheckIfMatch(c, userETag(o)); err != nil {
+			return err
+		}
+
+		if expectedRevision != 0 && expectedRevision != o.Profile.Revision {
+			return api.NewRevisionConflictError(expectedRevision, o.Profile.Revision)
+		}
+
+		o.Profile.Snapshot(o.Profile, virt)
+
+		if err := ctr.bindDataObject(c, tx, class, o.Profile, p); err != nil {
+			return err
+		}
+
+		o.Profile.Snapshot(o.Profile, virt)
+
+		if len(o.Profile.ChangesVirtual()) == 0 {
+			return nil
+		}
+
+		o.Profile.Revision++
+
+		return profileMgr.Update(o.Profile, "_data", "_files", "revision", "updated_at")
+	}); err != nil {
+		return uniqueViolationError(class, err)
+	}
+
+	serializer := serializers.UserSerializer{Class: class}
+
+	return api.RenderWithETag(c, http.StatusOK, serializer.Response(o), userETag(o))
 }
 
 func (ctr *Controller) UserAuth(c echo.Context) error {
@@ -185,13 +302,20 @@ func (ctr *Controller) UserDelete(c echo.Context) error {
 
 func (ctr *Controller) UserSchemaRetrieve(c echo.Context) error {
 	class := c.Get(contextUserClassKey).(*models.Class)
+	serializer := serializers.UserClassSerializer{}
+
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
+		return err
+	}
+
+	serializer.Projection = projection
 
-	var err error
 	if class.ObjectsCount, err = ctr.q.NewUserManager(c).CountEstimate(); err != nil {
 		return err
 	}
 
-	return api.Render(c, http.StatusOK, serializers.UserClassSerializer{}.Response(class))
+	return api.Render(c, http.StatusOK, serializer.Response(class))
 }
 
 func (ctr *Controller) UserSchemaUpdate(c echo.Context) error {
@@ -202,19 +326,34 @@ func (ctr *Controller) UserSchemaUpdate(c echo.Context) error {
 func (ctr *Controller) UserMeRetrieve(c echo.Context) error {
 	class := c.Get(contextUserClassKey).(*models.Class)
 	user := c.Get(settings.ContextUserKey).(*models.User)
+	serializer := serializers.UserSerializer{Class: class}
 
-	if err := ctr.q.NewUserManager(c).FetchData(class, user); err != nil {
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
 		return err
 	}
 
-	serializer := serializers.UserSerializer{Class: class}
+	serializer.Projection = projection
+
+	if serializer.Expansion, err = ctr.newExpansion(c, class); err != nil {
+		return err
+	}
+
+	if err = ctr.q.NewUserManager(c).FetchData(class, user); err != nil {
+		return err
+	}
+
+	if err = serializer.Prepare([]interface{}{user}); err != nil {
+		return err
+	}
 
 	return api.Render(c, http.StatusOK, serializer.ResponseWithGroup(user))
 }
 
 func (ctr *Controller) UserMeUpdate(c echo.Context) error {
-	// TODO: #15 Users updates/deletes
-	return api.NewPermissionDeniedError()
+	user := c.Get(settings.ContextUserKey).(*models.User)
+
+	return ctr.userUpdate(c, &models.User{ID: user.ID})
 }
 
 func (ctr *Controller) UserResetKey(c echo.Context) error {
@@ -256,6 +395,17 @@ func (ctr *Controller) UsersInGroupList(c echo.Context) error {
 	cursor := paginator.CreateCursor(c, true)
 	serializer := serializers.UserSerializer{Class: class}
 
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
+		return err
+	}
+
+	serializer.Projection = projection
+
+	if serializer.Expansion, err = ctr.newExpansion(c, class); err != nil {
+		return err
+	}
+
 	r, err := Paginate(c, cursor, (*models.User)(nil), serializer, paginator)
 	if err != nil {
 		return err
@@ -272,8 +422,20 @@ func (ctr *Controller) UsersInGroupRetrieve(c echo.Context) error {
 
 	class := c.Get(contextUserClassKey).(*models.Class)
 	group := c.Get(contextUserGroupKey).(*models.UserGroup)
+	serializer := serializers.UserSerializer{Class: class}
+
+	projection, err := parseProjection(c, serializer.FieldNames())
+	if err != nil {
+		return err
+	}
 
-	if err := ctr.q.NewUserManager(c).ForGroupByIDQ(class, group, o).Select(); err != nil {
+	serializer.Projection = projection
+
+	if serializer.Expansion, err = ctr.newExpansion(c, class); err != nil {
+		return err
+	}
+
+	if err = ctr.q.NewUserManager(c).ForGroupByIDQ(class, group, o).Select(); err != nil {
 		if err == pg.ErrNoRows {
 			return api.NewNotFoundError(o)
 		}
@@ -281,7 +443,9 @@ func (ctr *Controller) UsersInGroupRetrieve(c echo.Context) error {
 		return err
 	}
 
-	serializer := serializers.UserSerializer{Class: class}
+	if err = serializer.Prepare([]interface{}{o}); err != nil {
+		return err
+	}
 
 	return api.Render(c, http.StatusOK, serializer.Response(o))
 }
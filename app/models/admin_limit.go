@@ -88,3 +88,7 @@ func (m *AdminLimit) SchedulesCount(sub *Subscription) int {
 func (m *AdminLimit) InstancesCount(sub *Subscription) int {
 	return m.getLimit(sub, "instances_count", settings.Billing.InstancesCount)
 }
+
+func (m *AdminLimit) ImportRowsCount(sub *Subscription) int {
+	return m.getLimit(sub, "import_rows_count", settings.Billing.ImportRowsCount)
+}
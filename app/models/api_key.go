@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/Syncano/pkg-go/v2/database/fields"
 )
@@ -30,3 +31,28 @@ func (m *APIKey) String() string {
 func (m *APIKey) VerboseName() string {
 	return "API Key"
 }
+
+// IgnoreACL returns true if requests authenticated with API key bypass data object ACL and class permissions.
+func (m *APIKey) IgnoreACL() bool {
+	return m.boolOption("ignore_acl")
+}
+
+// CheckClassPermissions returns true if requests authenticated with API key opted into class permissions.
+func (m *APIKey) CheckClassPermissions() bool {
+	return m.boolOption("class_permissions")
+}
+
+func (m *APIKey) boolOption(name string) bool {
+	if m.Options.IsNull() {
+		return false
+	}
+
+	v, ok := m.Options.Map[name]
+	if !ok {
+		return false
+	}
+
+	b, _ := strconv.ParseBool(v.String)
+
+	return b
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAPIKeyIgnoreACL(t *testing.T) {
+	Convey("Given API key options", t, func() {
+		Convey("key without options does not ignore ACL", func() {
+			So((&APIKey{}).IgnoreACL(), ShouldBeFalse)
+		})
+		Convey("ignore_acl option is parsed as boolean", func() {
+			for val, expected := range map[string]bool{"true": true, "1": true, "false": false, "0": false, "yes": false} {
+				o := &APIKey{}
+				So(o.Options.Set(map[string]string{"ignore_acl": val}), ShouldBeNil)
+				So(o.IgnoreACL(), ShouldEqual, expected)
+			}
+		})
+		Convey("other options do not ignore ACL", func() {
+			o := &APIKey{}
+			So(o.Options.Set(map[string]string{"other": "true"}), ShouldBeNil)
+			So(o.IgnoreACL(), ShouldBeFalse)
+		})
+	})
+}
+
+func TestAPIKeyCheckClassPermissions(t *testing.T) {
+	Convey("Given API key options", t, func() {
+		Convey("class permissions are not checked by default", func() {
+			So((&APIKey{}).CheckClassPermissions(), ShouldBeFalse)
+		})
+		Convey("class_permissions option opts into class permissions", func() {
+			o := &APIKey{}
+			So(o.Options.Set(map[string]string{"class_permissions": "true"}), ShouldBeNil)
+			So(o.CheckClassPermissions(), ShouldBeTrue)
+			So(o.IgnoreACL(), ShouldBeFalse)
+		})
+	})
+}
@@ -3,6 +3,8 @@ package models
 import (
 	"context"
 	"fmt"
+	"hash/crc32"
+	"sort"
 	"time"
 
 	"github.com/jackc/pgtype"
@@ -13,6 +15,115 @@ import (
 
 const UserClassName = "user_profile"
 
+// Class permission levels for data objects. Every level includes lower ones.
+const (
+	ClassPermissionNone = iota
+	ClassPermissionRead
+	ClassPermissionList
+	ClassPermissionCreate
+)
+
+// ClassPermissionLevels maps names of class permission levels to their values.
+var ClassPermissionLevels = map[string]int{
+	"none":   ClassPermissionNone,
+	"read":   ClassPermissionRead,
+	"list":   ClassPermissionList,
+	"create": ClassPermissionCreate,
+}
+
+// ClassPermissionNames maps values of class permission levels to their names.
+var ClassPermissionNames = map[int]string{
+	ClassPermissionNone:   "none",
+	ClassPermissionRead:   "read",
+	ClassPermissionList:   "list",
+	ClassPermissionCreate: "create",
+}
+
+// Class index kinds and keys of index changes.
+const (
+	ClassIndexFilter = "filter"
+	ClassIndexOrder  = "order"
+	ClassIndexUnique = "unique"
+	ClassIndexAdd    = "+"
+	ClassIndexRemove = "-"
+)
+
+// ClassIndexes maps index kind to indexed field names and their mapping.
+type ClassIndexes map[string]map[string]string
+
+// NewClassIndexes decodes class indexes from JSON value.
+func NewClassIndexes(v interface{}) ClassIndexes {
+	ret := make(ClassIndexes)
+
+	m, _ := v.(map[string]interface{})
+	for kind, fieldsVal := range m {
+		fieldsMap, _ := fieldsVal.(map[string]interface{})
+		if len(fieldsMap) == 0 {
+			continue
+		}
+
+		ret[kind] = make(map[string]string, len(fieldsMap))
+
+		for name, mapping := range fieldsMap {
+			ret[kind][name], _ = mapping.(string)
+		}
+	}
+
+	return ret
+}
+
+// Diff returns indexes that are defined in other but not in i.
+func (i ClassIndexes) Diff(other ClassIndexes) ClassIndexes {
+	ret := make(ClassIndexes)
+
+	for kind, fieldsMap := range other {
+		for name, mapping := range fieldsMap {
+			if i[kind][name] == mapping {
+				continue
+			}
+
+			ret.set(kind, name, mapping)
+		}
+	}
+
+	return ret
+}
+
+// set sets mapping of indexed field.
+func (i ClassIndexes) set(kind, name, mapping string) {
+	if i[kind] == nil {
+		i[kind] = make(map[string]string)
+	}
+
+	i[kind][name] = mapping
+}
+
+// remove removes indexed field if it has specified mapping.
+func (i ClassIndexes) remove(kind, name, mapping string) {
+	if i[kind][name] != mapping {
+		return
+	}
+
+	delete(i[kind], name)
+
+	if len(i[kind]) == 0 {
+		delete(i, kind)
+	}
+}
+
+// ClassIndexName returns name of database index using specified method for class index of field mapping.
+func ClassIndexName(classID int, kind, method, mapping string) string {
+	return fmt.Sprintf("data_klass_%d_%s_%s_%08x", classID, kind, method, crc32.ChecksumIEEE([]byte(mapping)))
+}
+
+// ClassIndexChange is a single pending change of class index.
+type ClassIndexChange struct {
+	Action  string
+	Kind    string
+	Field   string
+	Mapping string
+}
+
 // Class represents Class model.
 type Class struct {
 	State
@@ -27,6 +138,7 @@ type Class struct {
 	Mapping         fields.Hstore
 	ExistingIndexes fields.JSON
 	IndexChanges    fields.JSON
+	MigrationError  string
 	Refs            fields.JSON
 	Visible         bool
 	CreatedAt       fields.Time
@@ -34,11 +146,34 @@ type Class struct {
 	Metadata        fields.JSON
 	Description     string
 
+	GroupID          int
+	Group            *UserGroup `msgpack:"-"`
+	GroupPermissions int        `pg:",use_zero"`
+	OtherPermissions int        `pg:",use_zero"`
+
 	computedSchema map[string]*DataObjectField
 	ObjectsCount   int           `pg:"-" msgpack:"-"`
 	Objects        []*DataObject `pg:"fk:_klass_id" msgpack:"-"`
 }
 
+func NewClass() *Class {
+	now := time.Now()
+	o := &Class{
+		IsLive:    true,
+		Revision:  1,
+		Visible:   true,
+		CreatedAt: fields.NewTime(&now),
+		UpdatedAt: fields.NewTime(&now),
+
+		GroupPermissions: ClassPermissionNone,
+		OtherPermissions: ClassPermissionCreate,
+	}
+
+	o.ExistingIndexes.Set(ClassIndexes{}) // nolint: errcheck
+
+	return o
+}
+
 func (m *Class) String() string {
 	return fmt.Sprintf("Class<ID=%d Name=%q>", m.ID, m.Name)
 }
@@ -68,6 +203,22 @@ func (m *Class) GetStatus() string {
 	return "ready"
 }
 
+// SetSchema sets schema of class with mapping of its fields.
+func (m *Class) SetSchema(schema []map[string]interface{}, mapping map[string]string) error {
+	if err := m.Schema.Set(schema); err != nil {
+		return err
+	}
+
+	m.Mapping = fields.NewHstore(nil)
+	for name, key := range mapping {
+		m.Mapping.Map[name] = pgtype.Text{String: key, Status: pgtype.Present}
+	}
+
+	m.computedSchema = nil
+
+	return nil
+}
+
 // ComputedSchema computes and returns schema map.
 func (m *Class) ComputedSchema() map[string]*DataObjectField {
 	tableAlias := "data_object"
@@ -102,6 +253,137 @@ func (m *Class) ComputedSchema() map[string]*DataObjectField {
 	return m.computedSchema
 }
 
+// Indexes returns indexes required by current schema.
+func (m *Class) Indexes() ClassIndexes {
+	ret := make(ClassIndexes)
+
+	for name, field := range m.ComputedSchema() {
+		for kind, indexed := range map[string]bool{
+			ClassIndexFilter: field.FilterIndex,
+			ClassIndexOrder:  field.OrderIndex,
+			ClassIndexUnique: field.Unique,
+		} {
+			if indexed {
+				ret.set(kind, name, field.Mapping)
+			}
+		}
+	}
+
+	return ret
+}
+
+// UpdateIndexChanges sets index changes needed to get from existing indexes to ones required by current schema.
+// Class stays locked until they are processed. Error of previous migration is cleared as changes are retried.
+func (m *Class) UpdateIndexChanges() error {
+	m.MigrationError = ""
+
+	changes := m.indexChanges(NewClassIndexes(m.ExistingIndexes.Get()))
+	if changes == nil {
+		return m.IndexChanges.Set(nil)
+	}
+
+	return m.IndexChanges.Set(changes)
+}
+
+// PendingIndexChanges returns index changes that are not yet processed. Removals go first so that indexes
+// of retyped fields are replaced.
+func (m *Class) PendingIndexChanges() []*ClassIndexChange {
+	var ret []*ClassIndexChange
+
+	changes := m.pendingIndexes()
+
+	for _, action := range []string{ClassIndexRemove, ClassIndexAdd} {
+		for _, kind := range []string{ClassIndexFilter, ClassIndexOrder, ClassIndexUnique} {
+			fieldsMap := changes[action][kind]
+			names := make([]string, 0, len(fieldsMap))
+
+			for name := range fieldsMap {
+				names = append(names, name)
+			}
+
+			sort.Strings(names)
+
+			for _, name := range names {
+				ret = append(ret, &ClassIndexChange{Action: action, Kind: kind, Field: name, Mapping: fieldsMap[name]})
+			}
+		}
+	}
+
+	return ret
+}
+
+// CompleteIndexChange marks index change as processed. Class is unlocked when there are no more pending changes.
+func (m *Class) CompleteIndexChange(ch *ClassIndexChange) error {
+	existing := NewClassIndexes(m.ExistingIndexes.Get())
+
+	if ch.Action == ClassIndexAdd {
+		existing.set(ch.Kind, ch.Field, ch.Mapping)
+	} else {
+		existing.remove(ch.Kind, ch.Field, ch.Mapping)
+	}
+
+	if err := m.ExistingIndexes.Set(existing); err != nil {
+		return err
+	}
+
+	return m.removeIndexChange(ch)
+}
+
+// FailIndexChange drops index change that could not be processed without changing existing indexes
+// and records its error. Schema is kept as is so that change is retried on next schema update.
+func (m *Class) FailIndexChange(ch *ClassIndexChange, detail string) error {
+	m.MigrationError = detail
+
+	return m.removeIndexChange(ch)
+}
+
+func (m *Class) removeIndexChange(ch *ClassIndexChange) error {
+	changes := m.pendingIndexes()
+	changes[ch.Action].remove(ch.Kind, ch.Field, ch.Mapping)
+
+	if len(changes[ch.Action]) == 0 {
+		delete(changes, ch.Action)
+	}
+
+	if len(changes) == 0 {
+		return m.IndexChanges.Set(nil)
+	}
+
+	return m.IndexChanges.Set(changes)
+}
+
+func (m *Class) pendingIndexes() map[string]ClassIndexes {
+	ret := make(map[string]ClassIndexes)
+
+	v, _ := m.IndexChanges.Get().(map[string]interface{})
+	for action, indexes := range v {
+		if idx := NewClassIndexes(indexes); len(idx) > 0 {
+			ret[action] = idx
+		}
+	}
+
+	return ret
+}
+
+func (m *Class) indexChanges(existing ClassIndexes) map[string]ClassIndexes {
+	wanted := m.Indexes()
+	ret := make(map[string]ClassIndexes)
+
+	if add := existing.Diff(wanted); len(add) > 0 {
+		ret[ClassIndexAdd] = add
+	}
+
+	if remove := wanted.Diff(existing); len(remove) > 0 {
+		ret[ClassIndexRemove] = remove
+	}
+
+	if len(ret) == 0 {
+		return nil
+	}
+
+	return ret
+}
+
 func (m *Class) FilterFields() map[string]FilterField {
 	filterFields := make(map[string]FilterField)
 	def := defaultObjectFilterFields
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClassFailIndexChange(t *testing.T) {
+	Convey("Given class with pending unique index", t, func() {
+		class := NewClass()
+		So(class.SetSchema([]map[string]interface{}{
+			{"name": "a", "type": FieldStringType, "unique": true},
+			{"name": "b", "type": FieldStringType, "order_index": true},
+		}, map[string]string{"a": "_a", "b": "_b"}), ShouldBeNil)
+		So(class.UpdateIndexChanges(), ShouldBeNil)
+
+		changes := class.PendingIndexChanges()
+		So(changes, ShouldHaveLength, 2)
+		So(changes[1].Kind, ShouldEqual, ClassIndexUnique)
+
+		Convey("failed change is dropped without becoming existing index", func() {
+			So(class.FailIndexChange(changes[1], "failed"), ShouldBeNil)
+			So(class.MigrationError, ShouldEqual, "failed")
+			So(class.PendingIndexChanges(), ShouldHaveLength, 1)
+			So(class.IsLocked(), ShouldBeTrue)
+			So(NewClassIndexes(class.ExistingIndexes.Get()), ShouldBeEmpty)
+			So(class.ComputedSchema()["a"].Unique, ShouldBeTrue)
+
+			Convey("class is unlocked once remaining changes are processed", func() {
+				So(class.CompleteIndexChange(changes[0]), ShouldBeNil)
+				So(class.IsLocked(), ShouldBeFalse)
+				So(class.MigrationError, ShouldEqual, "failed")
+				So(NewClassIndexes(class.ExistingIndexes.Get()), ShouldResemble,
+					ClassIndexes{ClassIndexOrder: {"b": "_b"}})
+			})
+			Convey("failed change is retried and error cleared on next schema update", func() {
+				So(class.UpdateIndexChanges(), ShouldBeNil)
+				So(class.MigrationError, ShouldBeEmpty)
+				So(class.PendingIndexChanges(), ShouldHaveLength, 2)
+			})
+		})
+	})
+}
@@ -8,6 +8,30 @@ import (
 	"github.com/Syncano/pkg-go/v2/database/fields"
 )
 
+// DataObject permission levels. Every level includes lower ones.
+const (
+	PermissionNone = iota
+	PermissionRead
+	PermissionWrite
+	PermissionFull
+)
+
+// PermissionLevels maps names of permission levels to their values.
+var PermissionLevels = map[string]int{
+	"none":  PermissionNone,
+	"read":  PermissionRead,
+	"write": PermissionWrite,
+	"full":  PermissionFull,
+}
+
+// PermissionNames maps values of permission levels to their names.
+var PermissionNames = map[int]string{
+	PermissionNone:  "none",
+	PermissionRead:  "read",
+	PermissionWrite: "write",
+	PermissionFull:  "full",
+}
+
 // DataObject represents DataObject model.
 type DataObject struct {
 	State
@@ -18,7 +42,7 @@ type DataObject struct {
 
 	ID        int
 	Data      fields.Hstore `pg:"_data" state:"virtual"`
-	Files     fields.Hstore `pg:"_files"`
+	Files     fields.Hstore `pg:"_files" state:"-"`
 	Revision  int
 	CreatedAt fields.Time
 	UpdatedAt fields.Time
@@ -27,6 +51,15 @@ type DataObject struct {
 	Owner   *User
 	ClassID int    `pg:"_klass_id"`
 	Class   *Class `pg:"fk:_klass_id"`
+
+	OwnerPermissions int `pg:",use_zero"`
+	GroupID          int
+	Group            *UserGroup
+	GroupPermissions int `pg:",use_zero"`
+	OtherPermissions int `pg:",use_zero"`
+
+	// Distances holds distances in meters from centers of radius lookups when they are selected by query.
+	Distances map[string]*float64 `pg:"-" msgpack:"-"`
 }
 
 func NewDataObject(class *Class) *DataObject {
@@ -41,6 +74,10 @@ func NewDataObject(class *Class) *DataObject {
 		Revision:  1,
 		CreatedAt: fields.NewTime(&now),
 		UpdatedAt: fields.NewTime(&now),
+
+		OwnerPermissions: PermissionFull,
+		GroupPermissions: PermissionNone,
+		OtherPermissions: PermissionNone,
 	}
 }
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -135,7 +136,6 @@ func ValueToString(typ string, val interface{}) (string, error) {
 		return val.(fields.Time).Time.UTC().Format(pgTimestamptzMinuteFormat), nil
 
 	case FieldFileType:
-		// TODO: #16 Object updates - file support
 		return val.(string), nil
 
 	case FieldReferenceType:
@@ -210,6 +210,29 @@ func NewObjectField(m interface{}, alias, fieldName, typ string) *SimpleObjectFi
 	return &SimpleObjectField{name: fieldName, typ: typ, field: table.FieldsMap[fieldName], table: alias}
 }
 
+// DefaultSearchLanguage is a text search configuration used when field doesn't define a valid one.
+const DefaultSearchLanguage = "simple"
+
+// SearchLanguages defines text search configurations available for string and text fields.
+var SearchLanguages = map[string]struct{}{
+	"simple":     {},
+	"danish":     {},
+	"dutch":      {},
+	"english":    {},
+	"finnish":    {},
+	"french":     {},
+	"german":     {},
+	"hungarian":  {},
+	"italian":    {},
+	"norwegian":  {},
+	"portuguese": {},
+	"romanian":   {},
+	"russian":    {},
+	"spanish":    {},
+	"swedish":    {},
+	"turkish":    {},
+}
+
 // DataObjectField is used to define each field in data object schema.
 type DataObjectField struct {
 	FName       string `mapstructure:"name"`
@@ -218,9 +241,20 @@ type DataObjectField struct {
 	FilterIndex bool   `mapstructure:"filter_index"`
 	Unique      bool   `mapstructure:"unique"`
 	Target      string `mapstructure:"target"`
+	Language    string `mapstructure:"language"`
+
+	// Constraints.
+	Required  bool        `mapstructure:"required"`
+	Default   interface{} `mapstructure:"default"`
+	Min       *float64    `mapstructure:"min"`
+	Max       *float64    `mapstructure:"max"`
+	MaxLength int         `mapstructure:"max_length"`
+	Pattern   string      `mapstructure:"pattern"`
 
 	TableAlias string
 	Mapping    string
+
+	pattern *regexp.Regexp
 }
 
 func (f *DataObjectField) Name() string {
@@ -239,6 +273,24 @@ func (f *DataObjectField) FromString(s string) (interface{}, error) {
 	return ValueFromString(f.FType, s)
 }
 
+// PatternRegexp returns compiled pattern constraint of field or nil if there is none.
+func (f *DataObjectField) PatternRegexp() *regexp.Regexp {
+	if f.pattern == nil && f.Pattern != "" {
+		f.pattern, _ = regexp.Compile(f.Pattern)
+	}
+
+	return f.pattern
+}
+
+// SearchLanguage returns text search configuration of field.
+func (f *DataObjectField) SearchLanguage() string {
+	if _, ok := SearchLanguages[f.Language]; ok {
+		return f.Language
+	}
+
+	return DefaultSearchLanguage
+}
+
 func (f *DataObjectField) SQLName() string {
 	var typ string
 
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	dataObjectImportListMaxSize = 100
+	dataObjectImportTTL         = 7 * 24 * time.Hour
+	dataObjectImportTrimmedTTL  = 24 * time.Hour
+
+	// DataObjectImportStaleTimeout is a time after which unfinished import without progress is considered interrupted.
+	DataObjectImportStaleTimeout = 10 * time.Minute
+)
+
+// DataObjectImportStatus enum.
+const (
+	DataObjectImportStatusPending    = "pending"
+	DataObjectImportStatusProcessing = "processing"
+	DataObjectImportStatusSuccess    = "success"
+	DataObjectImportStatusFailure    = "failure"
+)
+
+// DataObjectImport represents data object import redis model.
+type DataObjectImport struct {
+	ID          int
+	Status      string `default:"pending"`
+	Format      string
+	Detail      string
+	CreatedAt   time.Time
+	FinishedAt  time.Time
+	HeartbeatAt time.Time
+	Processed   int
+	Imported    int
+	Failed      int
+	Errors      map[string]interface{} `default:"{}"`
+}
+
+// IsInterrupted returns true if import is not finished and its progress was not saved for longer than stale timeout,
+// e.g. because process running it was restarted. Such import never finishes.
+func (m *DataObjectImport) IsInterrupted() bool {
+	return (m.Status == DataObjectImportStatusPending || m.Status == DataObjectImportStatusProcessing) &&
+		time.Since(m.HeartbeatAt) > DataObjectImportStaleTimeout
+}
+
+// VerboseName returns verbose name for model.
+func (m *DataObjectImport) VerboseName() string {
+	return "Data Object Import"
+}
+
+func (m *DataObjectImport) Key(args map[string]interface{}) string {
+	return fmt.Sprintf("%d:rdb:DataObjectImport", args["instance"].(*Instance).ID)
+}
+
+func (m *DataObjectImport) ListArgs(args map[string]interface{}) string {
+	return fmt.Sprintf("%d", args["class"].(*Class).ID)
+}
+
+func (m *DataObjectImport) ListMaxSize(args map[string]interface{}) int {
+	return dataObjectImportListMaxSize
+}
+
+func (m *DataObjectImport) TTL(args map[string]interface{}) time.Duration {
+	return dataObjectImportTTL
+}
+
+func (m *DataObjectImport) TrimmedTTL(args map[string]interface{}) time.Duration {
+	return dataObjectImportTrimmedTTL
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDataObjectImportIsInterrupted(t *testing.T) {
+	Convey("Given data object import", t, func() {
+		stale := time.Now().Add(-DataObjectImportStaleTimeout - time.Minute)
+
+		Convey("unfinished import with recent heartbeat is not interrupted", func() {
+			for _, status := range []string{DataObjectImportStatusPending, DataObjectImportStatusProcessing} {
+				So((&DataObjectImport{Status: status, HeartbeatAt: time.Now()}).IsInterrupted(), ShouldBeFalse)
+			}
+		})
+		Convey("unfinished import with stale heartbeat is interrupted", func() {
+			for _, status := range []string{DataObjectImportStatusPending, DataObjectImportStatusProcessing} {
+				So((&DataObjectImport{Status: status, HeartbeatAt: stale}).IsInterrupted(), ShouldBeTrue)
+			}
+		})
+		Convey("finished import is never interrupted", func() {
+			for _, status := range []string{DataObjectImportStatusSuccess, DataObjectImportStatusFailure} {
+				So((&DataObjectImport{Status: status, HeartbeatAt: stale}).IsInterrupted(), ShouldBeFalse)
+			}
+		})
+	})
+}
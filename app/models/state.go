@@ -115,13 +115,16 @@ func (s *State) changed(virtual, sqlnames bool) []string {
 			continue
 		}
 
-		if sqlnames {
-			k = s.sqlNames[k]
+		if v == s.after.hash[k] {
+			continue
 		}
 
-		if v != s.after.hash[k] {
-			dirty = append(dirty, k)
+		// Virtual fields have no sql name, keep their name as is.
+		if n, ok := s.sqlNames[k]; ok && sqlnames {
+			k = n
 		}
+
+		dirty = append(dirty, k)
 	}
 
 	return dirty
@@ -19,7 +19,7 @@ type ChannelManager struct {
 
 // NewChannelManager creates and returns new Channel manager.
 func (q *Factory) NewChannelManager(c echo.Context) *ChannelManager {
-	return &ChannelManager{Factory: q, LiveManager: manager.NewLiveTenantManager(WrapContext(c), q.db)}
+	return &ChannelManager{Factory: q, LiveManager: q.newLiveTenantManager(c)}
 }
 
 // OneByName outputs object filtered by name.
@@ -20,7 +20,7 @@ type ClassManager struct {
 
 // NewClassManager creates and returns new Class manager.
 func (q *Factory) NewClassManager(c echo.Context) *ClassManager {
-	return &ClassManager{Factory: q, LiveManager: manager.NewLiveTenantManager(WrapContext(c), q.db)}
+	return &ClassManager{Factory: q, LiveManager: q.newLiveTenantManager(c)}
 }
 
 // OneByName outputs object filtered by name.
@@ -39,6 +39,25 @@ func (m *ClassManager) OneByName(o *models.Class) error {
 	)
 }
 
+// ByIDQ returns one object filtered by id.
+func (m *ClassManager) ByIDQ(o *models.Class) *orm.Query {
+	return m.Query(o).
+		Where("?TableAlias.id = ?", o.ID)
+}
+
+// ByNameQ returns one object filtered by name, including classes that are not visible.
+func (m *ClassManager) ByNameQ(o *models.Class) *orm.Query {
+	return m.Query(o).
+		Where("?TableAlias.name = ?", o.Name)
+}
+
+// ReferencingQ outputs classes with fields that reference specified class.
+func (m *ClassManager) ReferencingQ(class *models.Class, o interface{}) *orm.Query {
+	return m.Query(o).
+		Where("?TableAlias.id != ?", class.ID).
+		Where("?TableAlias.refs::jsonb->'class' @> to_jsonb(?::text)", class.Name)
+}
+
 // WithAccessQ outputs objects that entity has access to.
 func (m *ClassManager) WithAccessQ(o interface{}) *orm.Query {
 	q := m.Query(o).
@@ -55,3 +74,10 @@ func (m *ClassManager) WithAccessByNameQ(o *models.Class) *orm.Query {
 	return m.WithAccessQ(o).
 		Where("?TableAlias.name = ?", o.Name)
 }
+
+// UserDefinedQ outputs visible classes excluding user profile class.
+func (m *ClassManager) UserDefinedQ(o interface{}) *orm.Query {
+	return m.Query(o).
+		Where("visible IS TRUE").
+		Where("name != ?", models.UserClassName)
+}
@@ -17,7 +17,7 @@ type DataObjectManager struct {
 
 // NewDataObjectManager creates and returns new DataObject manager.
 func (q *Factory) NewDataObjectManager(c echo.Context) *DataObjectManager {
-	return &DataObjectManager{Factory: q, LiveManager: manager.NewLiveTenantManager(WrapContext(c), q.db)}
+	return &DataObjectManager{Factory: q, LiveManager: q.newLiveTenantManager(c)}
 }
 
 // CountEstimate returns count estimate for current data objects list.
@@ -25,10 +25,38 @@ func (m *DataObjectManager) CountEstimate(q orm.QueryAppender) (int, error) {
 	return manager.CountEstimate(m.DBContext().Context(), m.DB(), q, settings.API.DataObjectEstimateThreshold)
 }
 
-// ForClassQ outputs objects within specific class.
+// ForClassQ outputs objects within specific class that are readable in current context.
 func (m *DataObjectManager) ForClassQ(class *models.Class, o interface{}) *orm.Query {
-	q := m.Query(o).Where("_klass_id = ?", class.ID)
-	return q
+	return m.WithPermissionQ(m.Query(o).Where("_klass_id = ?", class.ID), models.PermissionRead)
+}
+
+// ForProfileQ outputs user profile object. Profiles are not subject to data object ACL.
+func (m *DataObjectManager) ForProfileQ(class *models.Class, user *models.User, o *models.DataObject) *orm.Query {
+	return m.Query(o).Where("_klass_id = ?", class.ID).Where("owner_id = ?", user.ID)
+}
+
+// WithPermissionQ filters data objects query to ones that user of request has at least specified permission to.
+// It is a no-op when request is not authenticated with user key or API key ignores ACL.
+func (m *DataObjectManager) WithPermissionQ(q *orm.Query, perm int) *orm.Query {
+	c := m.DBContext().Unwrap().(echo.Context)
+
+	user, ok := c.Get(settings.ContextUserKey).(*models.User)
+	if !ok {
+		return q
+	}
+
+	if key, ok := c.Get(settings.ContextAPIKeyKey).(*models.APIKey); ok && key.IgnoreACL() {
+		return q
+	}
+
+	return q.WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+		q = q.WhereOr("?TableAlias.owner_id = ? AND ?TableAlias.owner_permissions >= ?", user.ID, perm).
+			WhereOr("?TableAlias.other_permissions >= ?", perm).
+			WhereOr("?TableAlias.group_permissions >= ? AND ?TableAlias.group_id IN "+
+				"(SELECT group_id FROM ?schema.users_membership WHERE user_id = ?)", perm, user.ID)
+
+		return q, nil
+	})
 }
 
 // ForClassByIDQ outputs one object within specific class filtered by id.
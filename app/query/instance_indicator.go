@@ -16,7 +16,7 @@ type InstanceIndicatorManager struct {
 
 // NewInstanceIndicatorManager creates and returns new Instance Indicator manager.
 func (q *Factory) NewInstanceIndicatorManager(c echo.Context) *InstanceIndicatorManager {
-	return &InstanceIndicatorManager{Factory: q, Manager: manager.NewTenantManager(WrapContext(c), q.db)}
+	return &InstanceIndicatorManager{Factory: q, Manager: q.newTenantManager(c)}
 }
 
 // ByInstanceAndTypeQ filters object filtered by instance and type.
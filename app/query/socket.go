@@ -19,7 +19,7 @@ type SocketManager struct {
 
 // NewSocketManager creates and returns new Socket manager.
 func (q *Factory) NewSocketManager(c echo.Context) *SocketManager {
-	return &SocketManager{Factory: q, LiveManager: manager.NewLiveTenantManager(WrapContext(c), q.db)}
+	return &SocketManager{Factory: q, LiveManager: q.newLiveTenantManager(c)}
 }
 
 // OneByID outputs object filtered by ID.
@@ -19,7 +19,7 @@ type SocketEndpointManager struct {
 
 // NewSocketEndpointManager creates and returns new Socket Endpoint manager.
 func (q *Factory) NewSocketEndpointManager(c echo.Context) *SocketEndpointManager {
-	return &SocketEndpointManager{Factory: q, Manager: manager.NewTenantManager(WrapContext(c), q.db)}
+	return &SocketEndpointManager{Factory: q, Manager: q.newTenantManager(c)}
 }
 
 // ForSocketQ outputs object filtered by name.
@@ -18,7 +18,7 @@ type SocketEnvironmentManager struct {
 
 // NewSocketEnvironmentManager creates and returns new Socket Environment manager.
 func (q *Factory) NewSocketEnvironmentManager(c echo.Context) *SocketEnvironmentManager {
-	return &SocketEnvironmentManager{Factory: q, LiveManager: manager.NewLiveTenantManager(WrapContext(c), q.db)}
+	return &SocketEnvironmentManager{Factory: q, LiveManager: q.newLiveTenantManager(c)}
 }
 
 // OneByID outputs object filtered by ID.
@@ -21,7 +21,7 @@ type TriggerManager struct {
 
 // NewTriggerManager creates and returns new Trigger manager.
 func (q *Factory) NewTriggerManager(c echo.Context) *TriggerManager {
-	return &TriggerManager{Factory: q, Manager: manager.NewTenantManager(WrapContext(c), q.db)}
+	return &TriggerManager{Factory: q, Manager: q.newTenantManager(c)}
 }
 
 // Match outputs one object within specific class filtered by id.
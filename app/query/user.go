@@ -19,7 +19,7 @@ type UserManager struct {
 
 // NewUserManager creates and returns new User manager.
 func (q *Factory) NewUserManager(c echo.Context) *UserManager {
-	return &UserManager{Factory: q, LiveManager: manager.NewLiveTenantManager(WrapContext(c), q.db)}
+	return &UserManager{Factory: q, LiveManager: q.newLiveTenantManager(c)}
 }
 
 // Q outputs objects query.
@@ -18,7 +18,7 @@ type UserGroupManager struct {
 
 // NewUserGroupManager creates and returns new User Group manager.
 func (q *Factory) NewUserGroupManager(c echo.Context) *UserGroupManager {
-	return &UserGroupManager{Factory: q, LiveManager: manager.NewLiveTenantManager(WrapContext(c), q.db)}
+	return &UserGroupManager{Factory: q, LiveManager: q.newLiveTenantManager(c)}
 }
 
 // Q outputs objects query.
@@ -16,7 +16,7 @@ type UserMembershipManager struct {
 
 // NewUserMembershipManager creates and returns new User Membership manager.
 func (q *Factory) NewUserMembershipManager(c echo.Context) *UserMembershipManager {
-	return &UserMembershipManager{Factory: q, Manager: manager.NewTenantManager(WrapContext(c), q.db)}
+	return &UserMembershipManager{Factory: q, Manager: q.newTenantManager(c)}
 }
 
 // Q outputs objects query.
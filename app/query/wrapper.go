@@ -1,14 +1,19 @@
 package query
 
 import (
+	"context"
+
 	"github.com/go-pg/pg/v9"
 	"github.com/labstack/echo/v4"
 
 	"github.com/Syncano/orion/app/api"
 	"github.com/Syncano/orion/app/settings"
 	"github.com/Syncano/pkg-go/v2/database"
+	"github.com/Syncano/pkg-go/v2/database/manager"
 )
 
+type txContextKey struct{}
+
 func WrapContext(c echo.Context) database.DBContext {
 	return database.WrapContextWithSchemaGetter(c.Request().Context(), func() string {
 		schema := c.Get(settings.ContextSchemaKey)
@@ -20,6 +25,35 @@ func WrapContext(c echo.Context) database.DBContext {
 	}, c)
 }
 
+// WithTx returns context with transaction that is shared by all tenant managers of requests using it.
+func WithTx(ctx context.Context, tx *pg.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, tx)
+}
+
+// ContextTx returns transaction shared by request or nil if there is none.
+func ContextTx(c echo.Context) *pg.Tx {
+	tx, _ := c.Request().Context().Value(txContextKey{}).(*pg.Tx)
+	return tx
+}
+
+func (q *Factory) newTenantManager(c echo.Context) *manager.Manager {
+	m := manager.NewTenantManager(WrapContext(c), q.db)
+	if tx := ContextTx(c); tx != nil {
+		m.SetDB(tx)
+	}
+
+	return m
+}
+
+func (q *Factory) newLiveTenantManager(c echo.Context) *manager.LiveManager {
+	m := manager.NewLiveTenantManager(WrapContext(c), q.db)
+	if tx := ContextTx(c); tx != nil {
+		m.SetDB(tx)
+	}
+
+	return m
+}
+
 func TenantDB(c echo.Context, db *database.DB) *pg.DB {
 	return db.TenantDB(c.Get(settings.ContextSchemaKey).(string))
 }
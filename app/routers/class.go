@@ -22,5 +22,6 @@ func ClassRegister(ctr *controllers.Controller, r *echo.Group, m *middlewares) {
 
 	// Sub routes.
 	sub := r.Group("/:class_name")
-	DataObjectRegister(ctr, sub.Group("/objects"), m.Add(ctr.ClassContext))
+	// ClassContext checks class permissions so it needs to run after auth.
+	DataObjectRegister(ctr, sub.Group("/objects"), m.AddAuth(ctr.ClassContext))
 }
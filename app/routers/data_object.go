@@ -3,7 +3,9 @@ package routers
 import (
 	"github.com/labstack/echo/v4"
 
+	"github.com/Syncano/orion/app/api"
 	"github.com/Syncano/orion/app/controllers"
+	"github.com/Syncano/orion/app/settings"
 )
 
 // DataObjectRegister registers data object routes.
@@ -13,6 +15,13 @@ func DataObjectRegister(ctr *controllers.Controller, r *echo.Group, m *middlewar
 	// List routes.
 	g.GET("/", ctr.DataObjectList)
 	g.POST("/", ctr.DataObjectCreate)
+	g.PATCH("/", ctr.DataObjectBulkUpdate)
+	g.DELETE("/", ctr.DataObjectBulkDelete)
+	g.GET("/aggregate/", ctr.DataObjectAggregate)
+	g.GET("/export/", ctr.DataObjectExport, api.ScopedRateLimit(m.limiter, "export", settings.API.DataObjectExportRateLimit))
+	g.GET("/import/", ctr.DataObjectImportList)
+	g.POST("/import/", ctr.DataObjectImportCreate)
+	g.GET("/import/:import_id/", ctr.DataObjectImportRetrieve)
 
 	// Detail routes.
 	d := g.Group("/:object_id")
@@ -29,4 +29,7 @@ func InstanceRegister(ctr *controllers.Controller, r *echo.Group, m *middlewares
 	UserRegister(ctr, sub.Group("/users"), m)
 	UserGroupRegister(ctr, sub.Group("/groups"), m)
 	SocketEndpointRegister(ctr, sub.Group("/endpoints/sockets"), m)
+
+	// Batch route.
+	sub.POST("/batch/", ctr.InstanceBatch, m.Get(ctr)...)
 }
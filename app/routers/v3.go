@@ -39,7 +39,8 @@ type middlewares struct {
 }
 
 func (m *middlewares) Get(ctr *controllers.Controller) []echo.MiddlewareFunc {
-	f := m.chain
+	// Cursor params need to be decoded before anything reads query params.
+	f := append([]echo.MiddlewareFunc{api.CursorParams}, m.chain...)
 
 	if m.DisableBody {
 		f = append(f, api.DisableBody)
@@ -6,15 +6,33 @@ import (
 )
 
 type ClassResponse struct {
-	Name         string      `json:"name"`
-	Description  string      `json:"description"`
-	Schema       fields.JSON `json:"schema"`
-	Status       string      `json:"status"`
-	CreatedAt    fields.Time `json:"created_at"`
-	UpdatedAt    fields.Time `json:"updated_at"`
-	ObjectsCount int         `json:"objects_count"`
-	Revision     int         `json:"revision"`
-	Metadata     fields.JSON `json:"metadata"`
+	Name           string      `json:"name"`
+	Description    string      `json:"description"`
+	Schema         fields.JSON `json:"schema"`
+	Status         string      `json:"status"`
+	MigrationError *string     `json:"migration_error"`
+	CreatedAt      fields.Time `json:"created_at"`
+	UpdatedAt      fields.Time `json:"updated_at"`
+	ObjectsCount   int         `json:"objects_count"`
+	Revision       int         `json:"revision"`
+	Metadata       fields.JSON `json:"metadata"`
+
+	Group            *int   `json:"group"`
+	GroupPermissions string `json:"group_permissions"`
+	OtherPermissions string `json:"other_permissions"`
+
+	PendingIndexes []*ClassIndexChangeResponse `json:"pending_indexes"`
+}
+
+type ClassIndexChangeResponse struct {
+	Action string `json:"action"`
+	Type   string `json:"type"`
+	Field  string `json:"field"`
+}
+
+var classIndexActions = map[string]string{
+	models.ClassIndexAdd:    "add",
+	models.ClassIndexRemove: "remove",
 }
 
 type ClassSerializer struct{}
@@ -31,6 +49,27 @@ func (s ClassSerializer) Response(i interface{}) interface{} {
 		ObjectsCount: o.ObjectsCount,
 		Revision:     o.Revision,
 		Metadata:     o.Metadata,
+
+		GroupPermissions: models.ClassPermissionNames[o.GroupPermissions],
+		OtherPermissions: models.ClassPermissionNames[o.OtherPermissions],
+
+		PendingIndexes: []*ClassIndexChangeResponse{},
+	}
+
+	for _, ch := range o.PendingIndexChanges() {
+		cls.PendingIndexes = append(cls.PendingIndexes, &ClassIndexChangeResponse{
+			Action: classIndexActions[ch.Action],
+			Type:   ch.Kind,
+			Field:  ch.Field,
+		})
+	}
+
+	if o.GroupID != 0 {
+		cls.Group = &o.GroupID
+	}
+
+	if o.MigrationError != "" {
+		cls.MigrationError = &o.MigrationError
 	}
 
 	return cls
@@ -0,0 +1,27 @@
+package serializers
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestClassSerializerMigrationError(t *testing.T) {
+	Convey("Given class", t, func() {
+		class := newTestClass("cls", map[string]interface{}{"name": "a", "type": models.FieldStringType})
+
+		Convey("migration error is null if migration did not fail", func() {
+			r := ClassSerializer{}.Response(class).(*ClassResponse)
+			So(r.Status, ShouldEqual, "ready")
+			So(r.MigrationError, ShouldBeNil)
+		})
+		Convey("migration error is reported along with status", func() {
+			class.MigrationError = "failed"
+			r := ClassSerializer{}.Response(class).(*ClassResponse)
+			So(r.Status, ShouldEqual, "ready")
+			So(*r.MigrationError, ShouldEqual, "failed")
+		})
+	})
+}
@@ -13,8 +13,40 @@ import (
 	"github.com/Syncano/pkg-go/v2/util"
 )
 
+const distanceField = "_distance"
+
+var dataObjectBaseFields = []string{
+	"id", "created_at", "updated_at", "revision",
+	"owner", "owner_permissions", "group", "group_permissions", "other_permissions",
+}
+
 type DataObjectSerializer struct {
-	Class *models.Class
+	Class      *models.Class
+	Projection *Projection
+	Expansion  *Expansion
+}
+
+func (s DataObjectSerializer) NeedsPrepare() bool {
+	return s.Expansion != nil
+}
+
+// Prepare loads expanded objects referenced by batch of data objects.
+func (s DataObjectSerializer) Prepare(objects []interface{}) error {
+	if s.Expansion == nil {
+		return nil
+	}
+
+	objs := make([]*models.DataObject, len(objects))
+	for i, o := range objects {
+		objs[i] = o.(*models.DataObject)
+	}
+
+	return s.Expansion.Prepare(objs)
+}
+
+// FieldNames returns names of fields available in response.
+func (s DataObjectSerializer) FieldNames() []string {
+	return append(append([]string{}, dataObjectBaseFields...), schemaFieldNames(s.Class)...)
 }
 
 func (s DataObjectSerializer) Response(i interface{}) interface{} {
@@ -24,14 +56,59 @@ func (s DataObjectSerializer) Response(i interface{}) interface{} {
 		"created_at": &o.CreatedAt,
 		"updated_at": &o.UpdatedAt,
 		"revision":   o.Revision,
+
+		"owner":             nullableID(o.OwnerID),
+		"owner_permissions": models.PermissionNames[o.OwnerPermissions],
+		"group":             nullableID(o.GroupID),
+		"group_permissions": models.PermissionNames[o.GroupPermissions],
+		"other_permissions": models.PermissionNames[o.OtherPermissions],
 	}
 
-	processDataObjectFields(s.Class, o, base)
+	processDataObjectFields(s.Class, o, s.Projection, base)
+
+	if s.Expansion != nil {
+		s.Expansion.Apply(o, s.Projection, base)
+	}
 
-	return base
+	return processDataObjectDistances(o, s.Projection.Apply(base))
 }
 
-func processDataObjectFields(class *models.Class, o *models.DataObject, m map[string]interface{}) {
+// nullableID returns nil for unset (zero) foreign key.
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+
+	return id
+}
+
+// schemaFieldNames returns names of schema fields in their declared order.
+func schemaFieldNames(class *models.Class) []string {
+	schema := class.ComputedSchema()
+	names := make([]string, 0, len(schema))
+
+	for _, f := range class.Schema.Get().([]interface{}) {
+		name, _ := f.(map[string]interface{})["name"].(string)
+
+		if _, ok := schema[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// processDataObjectDistances adds distances selected by query with radius lookups.
+// Distance is not a field of object so it is not subject to projection.
+func processDataObjectDistances(o *models.DataObject, m map[string]interface{}) map[string]interface{} {
+	if o.Distances != nil {
+		m[distanceField] = o.Distances
+	}
+
+	return m
+}
+
+func processDataObjectFields(class *models.Class, o *models.DataObject, p *Projection, m map[string]interface{}) {
 	// Serialize hstore fields.
 	var (
 		data map[string]pgtype.Text
@@ -43,6 +120,10 @@ func processDataObjectFields(class *models.Class, o *models.DataObject, m map[st
 	}
 
 	for _, field := range class.ComputedSchema() {
+		if !p.Includes(field.FName) {
+			continue
+		}
+
 		val = nil
 		if v, ok := data[field.Mapping]; ok && v.Status != pgtype.Null {
 			val = dataObjectFieldResponse(field, v.String)
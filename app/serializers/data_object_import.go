@@ -0,0 +1,53 @@
+package serializers
+
+import (
+	"github.com/Syncano/orion/app/models"
+	"github.com/Syncano/pkg-go/v2/database/fields"
+)
+
+type DataObjectImportResponse struct {
+	ID         int                    `json:"id"`
+	Status     string                 `json:"status"`
+	Format     string                 `json:"format"`
+	Detail     string                 `json:"detail,omitempty"`
+	CreatedAt  fields.Time            `json:"created_at"`
+	FinishedAt *fields.Time           `json:"finished_at"`
+	Processed  int                    `json:"processed"`
+	Imported   int                    `json:"imported"`
+	Failed     int                    `json:"failed"`
+	Errors     map[string]interface{} `json:"errors"`
+}
+
+const dataObjectImportInterruptedDetail = "Import was interrupted."
+
+type DataObjectImportSerializer struct{}
+
+// Response returns import with its status. Interrupted imports are reported as failed.
+func (s DataObjectImportSerializer) Response(i interface{}) interface{} {
+	o := i.(*models.DataObjectImport)
+	status, detail := o.Status, o.Detail
+
+	if o.IsInterrupted() {
+		status, detail = models.DataObjectImportStatusFailure, dataObjectImportInterruptedDetail
+	}
+
+	var finishedAt *fields.Time
+
+	if !o.FinishedAt.IsZero() {
+		t := fields.NewTime(&o.FinishedAt)
+		finishedAt = &t
+	}
+
+	return &DataObjectImportResponse{
+		ID:         o.ID,
+		Status:     status,
+		Format:     o.Format,
+		Detail:     detail,
+		CreatedAt:  fields.NewTime(&o.CreatedAt),
+		FinishedAt: finishedAt,
+		Processed:  o.Processed,
+		Imported:   o.Imported,
+		Failed:     o.Failed,
+		Errors:     o.Errors,
+	}
+}
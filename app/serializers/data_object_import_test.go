@@ -0,0 +1,39 @@
+package serializers
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestDataObjectImportSerializer(t *testing.T) {
+	Convey("Given data object import serializer", t, func() {
+		s := DataObjectImportSerializer{}
+
+		Convey("processing import with recent heartbeat is reported as is", func() {
+			o := &models.DataObjectImport{Status: models.DataObjectImportStatusProcessing, HeartbeatAt: time.Now()}
+			r := s.Response(o).(*DataObjectImportResponse)
+			So(r.Status, ShouldEqual, models.DataObjectImportStatusProcessing)
+			So(r.Detail, ShouldBeEmpty)
+		})
+		Convey("interrupted import is reported as failed", func() {
+			o := &models.DataObjectImport{
+				Status:      models.DataObjectImportStatusProcessing,
+				HeartbeatAt: time.Now().Add(-models.DataObjectImportStaleTimeout - time.Minute),
+			}
+			r := s.Response(o).(*DataObjectImportResponse)
+			So(r.Status, ShouldEqual, models.DataObjectImportStatusFailure)
+			So(r.Detail, ShouldEqual, dataObjectImportInterruptedDetail)
+			So(o.Status, ShouldEqual, models.DataObjectImportStatusProcessing)
+		})
+		Convey("finished import keeps its status and detail", func() {
+			o := &models.DataObjectImport{Status: models.DataObjectImportStatusFailure, Detail: "Storage limit exceeded."}
+			r := s.Response(o).(*DataObjectImportResponse)
+			So(r.Status, ShouldEqual, models.DataObjectImportStatusFailure)
+			So(r.Detail, ShouldEqual, "Storage limit exceeded.")
+		})
+	})
+}
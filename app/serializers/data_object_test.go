@@ -0,0 +1,52 @@
+package serializers
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func newTestClass(name string, schema ...map[string]interface{}) *models.Class {
+	mapping := make(map[string]string, len(schema))
+	for _, f := range schema {
+		mapping[f["name"].(string)] = "_" + f["name"].(string)
+	}
+
+	class := models.NewClass()
+	class.ID = 1
+	class.Name = name
+
+	So(class.SetSchema(schema, mapping), ShouldBeNil)
+
+	return class
+}
+
+func TestDataObjectSerializerDistances(t *testing.T) {
+	Convey("Given data object with distances selected by query", t, func() {
+		class := newTestClass("cls",
+			map[string]interface{}{"name": "loc", "type": models.FieldGeopointType},
+			map[string]interface{}{"name": "name", "type": models.FieldStringType},
+		)
+		dist := 12.5
+		o := models.NewDataObject(class)
+		o.Distances = map[string]*float64{"loc": &dist, "other": nil}
+
+		Convey("distances are returned as _distance", func() {
+			m := DataObjectSerializer{Class: class}.Response(o).(map[string]interface{})
+			So(m[distanceField], ShouldResemble, o.Distances)
+		})
+		Convey("distances are not affected by projection", func() {
+			m := DataObjectSerializer{Class: class, Projection: NewProjection([]string{"id"}, nil)}.Response(o).(map[string]interface{})
+			So(m, ShouldContainKey, "id")
+			So(m, ShouldNotContainKey, "name")
+			So(m[distanceField], ShouldResemble, o.Distances)
+		})
+		Convey("object without distances has no _distance", func() {
+			o.Distances = nil
+			m := DataObjectSerializer{Class: class}.Response(o).(map[string]interface{})
+			So(m, ShouldNotContainKey, distanceField)
+		})
+	})
+}
@@ -0,0 +1,98 @@
+package serializers
+
+import (
+	"github.com/Syncano/orion/app/models"
+)
+
+// Preparer is implemented by serializers that preload data for a batch of objects before serializing them.
+type Preparer interface {
+	NeedsPrepare() bool
+	Prepare(objects []interface{}) error
+}
+
+// ExpansionLoader returns serialized target objects of reference or relation field mapped by id.
+type ExpansionLoader func(f *models.DataObjectField, ids []int) (map[int]interface{}, error)
+
+// Expansion inlines serialized objects referenced by reference and relation fields.
+type Expansion struct {
+	Fields   []*models.DataObjectField
+	Load     ExpansionLoader
+	MaxItems int
+
+	values map[string]map[int]interface{}
+}
+
+// Prepare batch loads targets of expanded fields, one load per field.
+func (e *Expansion) Prepare(objects []*models.DataObject) error {
+	e.values = make(map[string]map[int]interface{}, len(e.Fields))
+
+	for _, f := range e.Fields {
+		ids := expansionIDs(f, objects, e.MaxItems)
+		if len(ids) == 0 {
+			continue
+		}
+
+		vals, err := e.Load(f, ids)
+		if err != nil {
+			return err
+		}
+
+		e.values[f.FName] = vals
+	}
+
+	return nil
+}
+
+// Apply replaces ids of expanded fields included in response with serialized objects.
+func (e *Expansion) Apply(o *models.DataObject, p *Projection, m map[string]interface{}) {
+	for _, f := range e.Fields {
+		if !p.Includes(f.FName) {
+			continue
+		}
+
+		vals := e.values[f.FName]
+
+		switch v := f.Get(o).(type) {
+		case int:
+			m[f.FName] = vals[v]
+
+		case []int:
+			lst := make([]interface{}, 0, len(v))
+
+			for _, id := range v {
+				if obj, ok := vals[id]; ok {
+					lst = append(lst, obj)
+				}
+			}
+
+			m[f.FName] = lst
+		}
+	}
+}
+
+// expansionIDs returns unique ids referenced by field limited to maxItems.
+func expansionIDs(f *models.DataObjectField, objects []*models.DataObject, maxItems int) []int {
+	var ids []int
+
+	seen := make(map[int]struct{})
+	add := func(id int) {
+		if _, ok := seen[id]; !ok && len(ids) < maxItems {
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+
+	for _, o := range objects {
+		switch v := f.Get(o).(type) {
+		case int:
+			add(v)
+
+		case []int:
+			for _, id := range v {
+				add(id)
+			}
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,73 @@
+package serializers
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestExpansion(t *testing.T) {
+	Convey("Given objects with reference and relation fields", t, func() {
+		class := newTestClass("cls",
+			map[string]interface{}{"name": "ref", "type": models.FieldReferenceType, "target": "self"},
+			map[string]interface{}{"name": "rel", "type": models.FieldRelationType, "target": "self"},
+		)
+		schema := class.ComputedSchema()
+		ref, rel := schema["ref"], schema["rel"]
+
+		o1 := models.NewDataObject(class)
+		o1.ID = 1
+		So(ref.Set(o1.Data, 2), ShouldBeNil)
+		So(rel.Set(o1.Data, []int{2, 3, 4}), ShouldBeNil)
+
+		o2 := models.NewDataObject(class)
+		o2.ID = 2
+		So(ref.Set(o2.Data, 3), ShouldBeNil)
+
+		loads := make(map[string][]int)
+		e := &Expansion{
+			Fields:   []*models.DataObjectField{ref, rel},
+			MaxItems: 10,
+			Load: func(f *models.DataObjectField, ids []int) (map[int]interface{}, error) {
+				loads[f.FName] = ids
+				ret := make(map[int]interface{})
+
+				for _, id := range ids {
+					if id != 4 {
+						ret[id] = map[string]interface{}{"id": id}
+					}
+				}
+
+				return ret, nil
+			},
+		}
+
+		Convey("targets are loaded once per field with unique ids", func() {
+			So(e.Prepare([]*models.DataObject{o1, o2}), ShouldBeNil)
+			So(loads, ShouldResemble, map[string][]int{"ref": {2, 3}, "rel": {2, 3, 4}})
+		})
+		Convey("loaded ids are limited by max items", func() {
+			e.MaxItems = 1
+			So(e.Prepare([]*models.DataObject{o1, o2}), ShouldBeNil)
+			So(loads, ShouldResemble, map[string][]int{"ref": {2}, "rel": {2}})
+		})
+		Convey("ids are replaced with serialized objects skipping missing ones", func() {
+			m := DataObjectSerializer{Class: class, Expansion: e}
+			So(m.Prepare([]interface{}{o1, o2}), ShouldBeNil)
+
+			r := m.Response(o1).(map[string]interface{})
+			So(r["ref"], ShouldResemble, map[string]interface{}{"id": 2})
+			So(r["rel"], ShouldResemble, []interface{}{map[string]interface{}{"id": 2}, map[string]interface{}{"id": 3}})
+		})
+		Convey("fields excluded by projection are not expanded", func() {
+			m := DataObjectSerializer{Class: class, Expansion: e, Projection: NewProjection(nil, []string{"rel"})}
+			So(m.Prepare([]interface{}{o1}), ShouldBeNil)
+
+			r := m.Response(o1).(map[string]interface{})
+			So(r["ref"], ShouldResemble, map[string]interface{}{"id": 2})
+			So(r, ShouldNotContainKey, "rel")
+		})
+	})
+}
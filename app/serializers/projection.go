@@ -0,0 +1,106 @@
+package serializers
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Projection limits fields included in response. Nil projection includes all fields.
+type Projection struct {
+	fields   map[string]struct{}
+	excluded map[string]struct{}
+}
+
+func NewProjection(fields, excluded []string) *Projection {
+	p := &Projection{excluded: make(map[string]struct{})}
+
+	if len(fields) > 0 {
+		p.fields = make(map[string]struct{})
+
+		for _, name := range fields {
+			p.fields[name] = struct{}{}
+		}
+	}
+
+	for _, name := range excluded {
+		p.excluded[name] = struct{}{}
+	}
+
+	return p
+}
+
+// Includes returns true if field should be serialized.
+func (p *Projection) Includes(name string) bool {
+	if p == nil {
+		return true
+	}
+
+	if p.fields != nil {
+		if _, ok := p.fields[name]; !ok {
+			return false
+		}
+	}
+
+	_, ok := p.excluded[name]
+
+	return !ok
+}
+
+// Apply removes fields that are not included from response map.
+func (p *Projection) Apply(m map[string]interface{}) map[string]interface{} {
+	if p == nil {
+		return m
+	}
+
+	for k := range m {
+		if !p.Includes(k) {
+			delete(m, k)
+		}
+	}
+
+	return m
+}
+
+// ApplyStruct converts response struct to map of included fields keyed by their JSON names.
+func (p *Projection) ApplyStruct(i interface{}) map[string]interface{} {
+	v := reflect.Indirect(reflect.ValueOf(i))
+	t := v.Type()
+	m := make(map[string]interface{})
+
+	for i := 0; i < t.NumField(); i++ {
+		name := jsonFieldName(t.Field(i))
+
+		if name != "" && p.Includes(name) {
+			m[name] = v.Field(i).Interface()
+		}
+	}
+
+	return m
+}
+
+// structFieldNames returns JSON names of response struct fields.
+func structFieldNames(i interface{}) []string {
+	t := reflect.Indirect(reflect.ValueOf(i)).Type()
+	names := make([]string, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonFieldName(t.Field(i)); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func jsonFieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "-" || f.PkgPath != "" {
+		return ""
+	}
+
+	if name == "" {
+		return f.Name
+	}
+
+	return name
+}
@@ -0,0 +1,71 @@
+package serializers
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+func TestProjection(t *testing.T) {
+	Convey("Given projection", t, func() {
+		Convey("nil projection includes all fields", func() {
+			var p *Projection
+
+			So(p.Includes("a"), ShouldBeTrue)
+			So(p.Apply(map[string]interface{}{"a": 1}), ShouldResemble, map[string]interface{}{"a": 1})
+		})
+		Convey("fields limit included fields", func() {
+			p := NewProjection([]string{"a", "b"}, nil)
+
+			So(p.Includes("a"), ShouldBeTrue)
+			So(p.Includes("c"), ShouldBeFalse)
+			So(p.Apply(map[string]interface{}{"a": 1, "c": 3}), ShouldResemble, map[string]interface{}{"a": 1})
+		})
+		Convey("excluded fields are skipped", func() {
+			p := NewProjection(nil, []string{"b"})
+
+			So(p.Includes("a"), ShouldBeTrue)
+			So(p.Includes("b"), ShouldBeFalse)
+		})
+		Convey("excluded fields take precedence over fields", func() {
+			p := NewProjection([]string{"a", "b"}, []string{"b"})
+
+			So(p.Includes("a"), ShouldBeTrue)
+			So(p.Includes("b"), ShouldBeFalse)
+		})
+		Convey("struct is converted to map keyed by JSON names", func() {
+			p := NewProjection([]string{"name", "revision"}, nil)
+			m := p.ApplyStruct(&UserClassResponse{Name: "user_profile", Revision: 2, Description: "desc"})
+
+			So(m, ShouldResemble, map[string]interface{}{"name": "user_profile", "revision": 2})
+		})
+	})
+}
+
+func TestSerializerFieldNames(t *testing.T) {
+	Convey("Given class with schema", t, func() {
+		class := newTestClass(models.UserClassName,
+			map[string]interface{}{"name": "c", "type": models.FieldStringType},
+			map[string]interface{}{"name": "a", "type": models.FieldIntegerType},
+			map[string]interface{}{"name": "b", "type": models.FieldTextType},
+		)
+
+		Convey("data object fields follow base fields in declared schema order", func() {
+			names := DataObjectSerializer{Class: class}.FieldNames()
+
+			So(names[:len(dataObjectBaseFields)], ShouldResemble, dataObjectBaseFields)
+			So(names[len(dataObjectBaseFields):], ShouldResemble, []string{"c", "a", "b"})
+		})
+		Convey("user fields start with profile fields in declared schema order", func() {
+			So(UserSerializer{Class: class}.FieldNames(), ShouldResemble,
+				append([]string{"c", "a", "b"}, userBaseFields...))
+		})
+		Convey("user class fields are JSON names of response", func() {
+			So(UserClassSerializer{}.FieldNames(), ShouldResemble, []string{
+				"name", "description", "schema", "status", "created_at", "updated_at", "objects_count", "revision", "metadata",
+			})
+		})
+	})
+}
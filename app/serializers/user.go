@@ -4,8 +4,35 @@ import (
 	"github.com/Syncano/orion/app/models"
 )
 
+var userBaseFields = []string{"id", "username", "user_key", "created_at", "updated_at", "revision", "groups"}
+
 type UserSerializer struct {
-	Class *models.Class
+	Class      *models.Class
+	Projection *Projection
+	Expansion  *Expansion
+}
+
+func (s UserSerializer) NeedsPrepare() bool {
+	return s.Expansion != nil
+}
+
+// Prepare loads expanded objects referenced by batch of users' profiles.
+func (s UserSerializer) Prepare(objects []interface{}) error {
+	if s.Expansion == nil {
+		return nil
+	}
+
+	objs := make([]*models.DataObject, len(objects))
+	for i, o := range objects {
+		objs[i] = o.(*models.User).Profile
+	}
+
+	return s.Expansion.Prepare(objs)
+}
+
+// FieldNames returns names of fields available in response.
+func (s UserSerializer) FieldNames() []string {
+	return append(schemaFieldNames(s.Class), userBaseFields...)
 }
 
 func (s UserSerializer) Response(i interface{}) interface{} {
@@ -19,9 +46,13 @@ func (s UserSerializer) Response(i interface{}) interface{} {
 		"revision":   o.Profile.Revision,
 	}
 
-	processDataObjectFields(s.Class, o.Profile, base)
+	processDataObjectFields(s.Class, o.Profile, s.Projection, base)
 
-	return base
+	if s.Expansion != nil {
+		s.Expansion.Apply(o.Profile, s.Projection, base)
+	}
+
+	return processDataObjectDistances(o.Profile, s.Projection.Apply(base))
 }
 
 func (s UserSerializer) ResponseWithGroup(i interface{}) interface{} {
@@ -29,6 +60,10 @@ func (s UserSerializer) ResponseWithGroup(i interface{}) interface{} {
 	gSerializer := UserGroupSerializer{}
 	base := s.Response(i).(map[string]interface{})
 
+	if !s.Projection.Includes("groups") {
+		return base
+	}
+
 	groups := make([]interface{}, 0)
 	for _, group := range o.Groups {
 		groups = append(groups, gSerializer.ShortResponse(group))
@@ -17,7 +17,14 @@ type UserClassResponse struct {
 	Metadata     fields.JSON `json:"metadata"`
 }
 
-type UserClassSerializer struct{}
+type UserClassSerializer struct {
+	Projection *Projection
+}
+
+// FieldNames returns names of fields available in response.
+func (s UserClassSerializer) FieldNames() []string {
+	return structFieldNames(UserClassResponse{})
+}
 
 func (s UserClassSerializer) Response(i interface{}) interface{} {
 	o := i.(*models.Class)
@@ -33,5 +40,9 @@ func (s UserClassSerializer) Response(i interface{}) interface{} {
 		Metadata:     o.Metadata,
 	}
 
+	if s.Projection != nil {
+		return s.Projection.ApplyStruct(cls)
+	}
+
 	return cls
 }
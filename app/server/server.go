@@ -61,7 +61,7 @@ func (s *Server) setupRouter() *echo.Echo {
 	// Top-down middlewares
 	e.Use(
 		echo_middleware.RequestID(),
-		middleware.CORSWithConfig(middleware.CORSConfig{MaxAge: 86400}),
+		middleware.CORSWithConfig(middleware.CORSConfig{MaxAge: 86400, ExposeHeaders: []string{api.HeaderETag}}),
 		echo_middleware.OpenCensus(),
 		sentryecho.New(sentryecho.Options{
 			Repanic: true,
@@ -72,6 +72,7 @@ type billing struct {
 	ClassesCount       PlanLimit
 	SocketsCount       PlanLimit
 	SchedulesCount     PlanLimit
+	ImportRowsCount    PlanLimit
 }
 
 var Billing = &billing{
@@ -90,6 +91,7 @@ var Billing = &billing{
 	ClassesCount:       PlanLimit{Default: 0, Paid: 100, Builder: 32},
 	SocketsCount:       PlanLimit{Default: 0, Paid: 100, Builder: 32},
 	SchedulesCount:     PlanLimit{Default: 0, Paid: 100, Builder: 32},
+	ImportRowsCount:    PlanLimit{Default: 0, Paid: -1, Builder: 100000},
 }
 
 type api struct {
@@ -99,17 +101,27 @@ type api struct {
 
 	MaxPayloadSize int64 `env:"MAX_PAYLOAD_SIZE"`
 	MaxPageSize    int   `env:"MAX_PAGE_SIZE"`
+	BatchMax       int   `env:"BATCH_MAX"`
+
+	ClassFieldsMax int `env:"CLASS_FIELDS_MAX"`
 
 	DataObjectEstimateThreshold int `env:"DATA_OBJECT_ESTIMATE_THRESHOLD"`
 	DataObjectNestedQueryLimit  int `env:"DATA_OBJECT_NESTED_QUERY_LIMIT"`
 	DataObjectNestedQueriesMax  int `env:"DATA_OBJECT_NESTED_QUERIES_MAX"`
+	DataObjectQueryDepthMax     int `env:"DATA_OBJECT_QUERY_DEPTH_MAX"`
+	DataObjectQueryClausesMax   int `env:"DATA_OBJECT_QUERY_CLAUSES_MAX"`
+	DataObjectMaxSize           int `env:"DATA_OBJECT_MAX_SIZE"`
+	DataObjectBulkMax           int `env:"DATA_OBJECT_BULK_MAX"`
+	DataObjectImportsMax        int `env:"DATA_OBJECT_IMPORTS_MAX"`
+	DataObjectOrderFieldsMax    int `env:"DATA_OBJECT_ORDER_FIELDS_MAX"`
 
 	ChannelWebSocketLimit   int
 	ChannelSubscribeTimeout time.Duration
 
-	AnonRateLimit     *RateData
-	AdminRateLimit    *RateData
-	InstanceRateLimit *RateData
+	AnonRateLimit             *RateData
+	AdminRateLimit            *RateData
+	InstanceRateLimit         *RateData
+	DataObjectExportRateLimit *RateData
 }
 
 var API = &api{
@@ -119,10 +131,19 @@ var API = &api{
 
 	MaxPayloadSize: 128 << 20,
 	MaxPageSize:    500,
+	BatchMax:       50,
+
+	ClassFieldsMax: 32,
 
 	DataObjectEstimateThreshold: 1000,
 	DataObjectNestedQueriesMax:  4,
+	DataObjectQueryDepthMax:     3,
+	DataObjectQueryClausesMax:   32,
 	DataObjectNestedQueryLimit:  1000,
+	DataObjectMaxSize:           32 << 10,
+	DataObjectBulkMax:           100,
+	DataObjectImportsMax:        2,
+	DataObjectOrderFieldsMax:    3,
 
 	ChannelWebSocketLimit:   100,
 	ChannelSubscribeTimeout: 5 * time.Minute,
@@ -130,6 +151,8 @@ var API = &api{
 	AnonRateLimit:     &RateData{Limit: 7, Duration: time.Second},
 	AdminRateLimit:    &RateData{Limit: 15, Duration: time.Second},
 	InstanceRateLimit: &RateData{Limit: 60, Duration: time.Second},
+
+	DataObjectExportRateLimit: &RateData{Limit: 2, Duration: time.Minute},
 }
 
 type socket struct {
@@ -0,0 +1,12 @@
+package validators
+
+type BatchRequestForm struct {
+	Method string      `form:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	Path   string      `form:"path" validate:"required,startswith=/,max=1024"`
+	Body   interface{} `form:"body"`
+}
+
+type BatchForm struct {
+	Requests     []*BatchRequestForm `form:"requests" validate:"required,min=1,dive,required"`
+	AllOrNothing bool                `form:"all_or_nothing"`
+}
@@ -0,0 +1,157 @@
+package validators
+
+import (
+	"github.com/go-pg/pg/v9/orm"
+
+	"github.com/Syncano/orion/app/models"
+)
+
+type ClassFieldForm struct {
+	Name        string `form:"name" validate:"required,max=64"`
+	Type        string `form:"type" validate:"required,oneof=string text integer float boolean datetime file reference relation object array geopoint"`
+	Target      string `form:"target" validate:"max=64"`
+	FilterIndex bool   `form:"filter_index"`
+	OrderIndex  bool   `form:"order_index"`
+	Unique      bool   `form:"unique"`
+	Language    string `form:"language"`
+
+	Required  bool        `form:"required"`
+	Default   interface{} `form:"default"`
+	Min       *float64    `form:"min"`
+	Max       *float64    `form:"max"`
+	MaxLength int         `form:"max_length" validate:"min=0"`
+	Pattern   string      `form:"pattern" validate:"max=256"`
+}
+
+// Field returns data object field defined by form.
+func (f *ClassFieldForm) Field() *models.DataObjectField {
+	return &models.DataObjectField{
+		FName:       f.Name,
+		FType:       f.Type,
+		OrderIndex:  f.OrderIndex,
+		FilterIndex: f.FilterIndex,
+		Unique:      f.Unique,
+		Target:      f.Target,
+		Language:    f.Language,
+		Required:    f.Required,
+		Default:     f.Default,
+		Min:         f.Min,
+		Max:         f.Max,
+		MaxLength:   f.MaxLength,
+		Pattern:     f.Pattern,
+	}
+}
+
+// Map returns schema definition of field.
+func (f *ClassFieldForm) Map() map[string]interface{} {
+	m := map[string]interface{}{"name": f.Name, "type": f.Type}
+
+	if f.Target != "" {
+		m["target"] = f.Target
+	}
+
+	if f.Language != "" {
+		m["language"] = f.Language
+	}
+
+	for key, val := range map[string]bool{
+		"filter_index": f.FilterIndex, "order_index": f.OrderIndex, "unique": f.Unique, "required": f.Required,
+	} {
+		if val {
+			m[key] = true
+		}
+	}
+
+	for key, val := range map[string]*float64{"min": f.Min, "max": f.Max} {
+		if val != nil {
+			m[key] = *val
+		}
+	}
+
+	if f.Default != nil {
+		m["default"] = f.Default
+	}
+
+	if f.MaxLength > 0 {
+		m["max_length"] = f.MaxLength
+	}
+
+	if f.Pattern != "" {
+		m["pattern"] = f.Pattern
+	}
+
+	return m
+}
+
+type ClassForm struct {
+	ClassQ *orm.Query
+	GroupQ *orm.Query
+	// Validate:
+	// sql_notexists: make sure ! ClassQ.Where(name=this_value).Exists()
+	Name        string                 `form:"name" validate:"required,max=64,sql_notexists=name"`
+	Description string                 `form:"description" validate:"max=256"`
+	Schema      []*ClassFieldForm      `form:"schema" validate:"dive,required"`
+	Metadata    map[string]interface{} `form:"metadata"`
+	// sql_exists: make sure GroupQ.Where(id=this_value).Exists()
+	Group            int    `form:"group" validate:"omitempty,sql_exists"`
+	GroupPermissions string `form:"group_permissions" validate:"omitempty,oneof=none read list create"`
+	OtherPermissions string `form:"other_permissions" validate:"omitempty,oneof=none read list create"`
+}
+
+func (f *ClassForm) Bind(m *models.Class) error {
+	m.Name = f.Name
+	m.Description = f.Description
+	m.GroupID = f.Group
+
+	if f.GroupPermissions != "" {
+		m.GroupPermissions = models.ClassPermissionLevels[f.GroupPermissions]
+	}
+
+	if f.OtherPermissions != "" {
+		m.OtherPermissions = models.ClassPermissionLevels[f.OtherPermissions]
+	}
+
+	metadata := f.Metadata
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+
+	return m.Metadata.Set(metadata)
+}
+
+type ClassUpdateForm struct {
+	GroupQ *orm.Query
+	// Validate:
+	// sql_exists: make sure GroupQ.Where(id=this_value).Exists()
+	Description      *string                `form:"description" validate:"omitempty,max=256"`
+	Schema           *[]*ClassFieldForm     `form:"schema" validate:"omitempty,dive,required"`
+	Metadata         map[string]interface{} `form:"metadata"`
+	Group            *int                   `form:"group" validate:"omitempty,sql_exists"`
+	GroupPermissions *string                `form:"group_permissions" validate:"omitempty,oneof=none read list create"`
+	OtherPermissions *string                `form:"other_permissions" validate:"omitempty,oneof=none read list create"`
+}
+
+// Bind sets fields defined in form. Group set to 0 removes class group.
+func (f *ClassUpdateForm) Bind(m *models.Class) error {
+	if f.Description != nil {
+		m.Description = *f.Description
+	}
+
+	if f.Group != nil {
+		m.GroupID = *f.Group
+	}
+
+	if f.GroupPermissions != nil {
+		m.GroupPermissions = models.ClassPermissionLevels[*f.GroupPermissions]
+	}
+
+	if f.OtherPermissions != nil {
+		m.OtherPermissions = models.ClassPermissionLevels[*f.OtherPermissions]
+	}
+
+	if f.Metadata != nil {
+		return m.Metadata.Set(f.Metadata)
+	}
+
+	return nil
+}
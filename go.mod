@@ -13,6 +13,7 @@ require (
 	github.com/cespare/reflex v0.3.0
 	github.com/cpuguy83/go-md2man/v2 v2.0.0 // indirect
 	github.com/delicb/gstring v1.0.0
+	github.com/fxamacker/cbor/v2 v2.3.0
 	github.com/getsentry/sentry-go v0.7.0
 	github.com/go-pg/pg/v9 v9.1.7
 	github.com/go-playground/locales v0.13.0
@@ -37,6 +38,7 @@ require (
 	github.com/twpayne/go-geom v1.3.4
 	github.com/urfave/cli/v2 v2.2.0
 	github.com/vektra/mockery v1.1.2
+	github.com/vmihailenco/msgpack/v4 v4.3.12
 	go.opencensus.io v0.22.4
 	go.uber.org/zap v1.15.0
 	golang.org/x/tools v0.0.0-20200827163409-021d7c6f1ec3